@@ -0,0 +1,954 @@
+package burnup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Magic values for JIRA export CSV field names
+const fieldIssueID string = "Issue key"
+const fieldIssueKey string = "Issue id"
+const fieldIssueType string = "Issue Type"
+const fieldStatus string = "Status"
+const fieldCreated string = "Created"
+const fieldResolved string = "Resolved"
+const fieldLabels string = "Labels"
+const fieldPoints string = "Custom field (Story point estimate)"
+const fieldParentKey string = "Parent"
+const fieldUpdated string = "Updated"
+const fieldAssignee string = "Assignee"
+const fieldSummary string = "Summary" // Exported by JIRA but not used
+
+// Default column names of the fields without an option of their own, keyed by the field name given to MapField
+var defaultColumns = map[string]string{
+	"id":       fieldIssueID,
+	"key":      fieldIssueKey,
+	"status":   fieldStatus,
+	"created":  fieldCreated,
+	"labels":   fieldLabels,
+	"points":   fieldPoints,
+	"parent":   fieldParentKey,
+	"updated":  fieldUpdated,
+	"assignee": fieldAssignee,
+}
+
+// In memory backlog record structure
+type backlogItem struct {
+	itemType      string
+	id            string
+	status        string
+	parent        string
+	hasChildren   bool
+	opened        time.Time
+	closed        time.Time
+	points        float64
+	estimate      float64 // Story points as estimated in JIRA, retained even when points are zeroed for a parent
+	tags          string
+	sprint        string
+	assignee      string
+	project       string // Project of the export the item was read from
+	resolution    string
+	group         string
+	invalidPoints bool      // Story points were given but could not be parsed
+	remaining     float64   // Points remaining according to the remaining estimate when counting partial progress
+	hasRemaining  bool      // Whether a remaining estimate was given
+	updated       time.Time // Date last updated, when partial progress is counted as closed
+	reopened      bool      // Resolved in the past but now in a reopen status, so its close is reversed on the run date
+}
+
+// Dynamically determined column IDs for attributes in CSV import file
+type columnIndexes struct {
+	issueID    int   // ID
+	issueKey   int   // Unique record ID
+	issueType  int   // Type (bug, defect, epic, etc.)
+	status     int   // Status (in progress, done, etc.)
+	created    int   // Date created
+	resolved   int   // Date resolved
+	labels     []int // Labels or tags, which JIRA may spread across several columns of the same name
+	points     []int // Story points, summed across the columns when there are several
+	parentKey  int   // Parent's unique record ID
+	sprint     int   // Sprint name (-1 when the export has no sprint column)
+	updated    int   // Date last updated (-1 when the export has no updated column)
+	resolution int   // Resolution (-1 when no resolution column is in use)
+	assignee   int   // Person the item is assigned to (-1 when the export has no assignee column)
+	group      int   // Grouping dimension such as component or team (-1 when not grouping)
+	remaining  int   // Remaining estimate (-1 when partial progress is not counted)
+}
+
+// Backlog is a backlog imported from a JIRA export
+type Backlog struct {
+	RowsProcessed int  // Number of data rows read from the export
+	HasSprints    bool // Whether the export contains the sprint column
+	HasAssignees  bool // Whether the export contains the assignee column
+	FromSnapshot  bool // Whether the backlog was read back from a snapshot rather than parsed from an export
+
+	items    map[string]backlogItem // Backlog items keyed by their unique record ID
+	belowMin []backlogItem          // Leaf items dropped for carrying fewer points than the minimum
+}
+
+// Normalize a CSV field name so that header matching ignores case and surrounding whitespace
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Return the value of an optional field or an empty string when the export does not contain it
+func optionalField(records []string, ndx int) string {
+	if ndx < 0 || ndx >= len(records) {
+		return ""
+	}
+	return records[ndx]
+}
+
+// Look up the position of an optional column returning -1 when it is not named or not in the export
+func optionalColumn(columnIndexMap map[string]int, name string) int {
+	if name == "" {
+		return -1
+	}
+	ndx, ok := columnIndexMap[normalizeFieldName(name)]
+	if !ok {
+		return -1
+	}
+	return ndx
+}
+
+// Join the non-empty values of the fields at the given positions of a record with spaces
+func joinFields(records []string, ndxs []int) string {
+	var values []string
+	for _, ndx := range ndxs {
+		if value := optionalField(records, ndx); value != "" {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// Parse a story point value.  When estimate units are in use, a value may end in one of the units, such as
+// 3d, which is converted to points at the unit's rate.  The longest unit matching wins
+func parsePoints(value string, opts Options) (float64, error) {
+	value = strings.TrimSpace(value)
+	if opts.DecimalComma {
+		value = strings.Replace(value, ",", ".", -1)
+	}
+	rate := 1.0
+	suffix := ""
+	for unit, unitPoints := range opts.UnitPoints {
+		if len(unit) > len(suffix) && strings.HasSuffix(strings.ToLower(value), strings.ToLower(unit)) {
+			suffix = unit
+			rate = unitPoints
+		}
+	}
+	value = strings.TrimSpace(value[:len(value)-len(suffix)])
+	points, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return points * rate, nil
+}
+
+// Start of an ISO 8601 timestamp as some JIRA configurations export, e.g. 2024-01-02T15:04:05.000+0000
+var isoTimestampPrefix = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}`)
+
+// Layouts of the ISO 8601 timestamps JIRA exports, which carry their own UTC offset with or without a colon
+var isoTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999-0700", "2006-01-02T15:04-0700", "2006-01-02T15:04Z07:00"}
+
+// Parse an ISO 8601 timestamp in any of the layouts JIRA exports
+func parseISOTimestamp(value string) (time.Time, error) {
+	var err error
+	for _, layout := range isoTimestampLayouts {
+		var date time.Time
+		date, err = time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Parse a JIRA timestamp in the time zone of the JIRA instance, converting it to the reporting time zone
+// when one is given so that it falls on the right day
+func parseJiraDate(value string, opts Options) (time.Time, error) {
+	instanceZone := opts.InstanceZone
+	if instanceZone == nil {
+		instanceZone = time.UTC
+	}
+	var date time.Time
+	var err error
+	if isoTimestampPrefix.MatchString(value) {
+		date, err = parseISOTimestamp(value)
+	} else {
+		date, err = time.ParseInLocation(jiraDate, value, instanceZone)
+	}
+	if err != nil {
+		return date, err
+	}
+	if opts.Zone != nil {
+		date = date.In(opts.Zone)
+	}
+	return date, nil
+}
+
+// Dynamically determine the position in the CSV record of the fields we need from the header row
+func resolveColumns(header []string, opts Options) columnIndexes {
+	columnIndexMap := make(map[string]int)
+	for i, val := range header {
+		columnIndexMap[normalizeFieldName(val)] = i
+	}
+	ndx := columnIndexes{
+		issueID:   columnIndexMap[normalizeFieldName(opts.column("id"))],
+		issueKey:  columnIndexMap[normalizeFieldName(opts.column("key"))],
+		issueType: columnIndexMap[normalizeFieldName(opts.TypeField)],
+		status:    columnIndexMap[normalizeFieldName(opts.column("status"))],
+		created:   columnIndexMap[normalizeFieldName(opts.column("created"))],
+		resolved:  columnIndexMap[normalizeFieldName(opts.ClosedField)],
+		parentKey: columnIndexMap[normalizeFieldName(opts.ParentField)],
+	}
+	pointsFields := opts.PointsFields
+	if len(pointsFields) == 0 {
+		pointsFields = []string{opts.column("points")}
+	}
+	for _, name := range pointsFields {
+		if column, ok := columnIndexMap[normalizeFieldName(name)]; ok {
+			ndx.points = append(ndx.points, column)
+		}
+	}
+	required := append([]string{opts.column("id"), opts.column("key"), opts.TypeField, opts.column("status"), opts.column("created"), opts.ClosedField, opts.column("labels"), opts.ParentField}, pointsFields...)
+	for _, name := range required {
+		if _, ok := columnIndexMap[normalizeFieldName(name)]; !ok {
+			warnf(opts, warnMissingColumn, "", 1, "The export has no \"%s\" column", name)
+		}
+	}
+	for i, val := range header {
+		if normalizeFieldName(val) == normalizeFieldName(opts.column("labels")) {
+			ndx.labels = append(ndx.labels, i)
+		}
+	}
+	ndx.sprint = optionalColumn(columnIndexMap, opts.SprintField)
+	ndx.updated = optionalColumn(columnIndexMap, opts.column("updated"))
+	ndx.resolution = optionalColumn(columnIndexMap, opts.ResolutionField)
+	ndx.assignee = optionalColumn(columnIndexMap, opts.column("assignee"))
+	ndx.group = optionalColumn(columnIndexMap, opts.GroupBy)
+	ndx.remaining = -1
+	if opts.Partial {
+		ndx.remaining = optionalColumn(columnIndexMap, opts.RemainingField)
+	}
+	return ndx
+}
+
+// List the columns of the header which are neither known JIRA fields nor named in the options
+func unexpectedColumns(header []string, opts Options) []string {
+	expected := map[string]bool{
+		normalizeFieldName(fieldSummary):         true,
+		normalizeFieldName(fieldIssueType):       true,
+		normalizeFieldName(fieldResolved):        true,
+		normalizeFieldName(opts.TypeField):       true,
+		normalizeFieldName(opts.ParentField):     true,
+		normalizeFieldName(opts.ClosedField):     true,
+		normalizeFieldName(opts.SprintField):     true,
+		normalizeFieldName(opts.ResolutionField): true,
+		normalizeFieldName(opts.GroupBy):         true,
+		normalizeFieldName(opts.RemainingField):  true,
+	}
+	for field, column := range defaultColumns {
+		expected[normalizeFieldName(column)] = true
+		expected[normalizeFieldName(opts.column(field))] = true
+	}
+	for _, column := range opts.PointsFields {
+		expected[normalizeFieldName(column)] = true
+	}
+	var unexpected []string
+	for _, column := range header {
+		if !expected[normalizeFieldName(column)] {
+			unexpected = append(unexpected, column)
+		}
+	}
+	return unexpected
+}
+
+// ParseBacklog reads a JIRA CSV export into a backlog.  Parents have their points zeroed so that only leaf
+// items carry points
+func ParseBacklog(in io.Reader, opts Options) (*Backlog, error) {
+
+	backlog := &Backlog{
+		items: make(map[string]backlogItem),
+	}
+	backlogMap := backlog.items
+
+	// Read the input treating it as a csv
+	r := csv.NewReader(in)
+	r.LazyQuotes = true
+	r.Comma = opts.Delimiter
+
+	// Parse into a map of stories
+	var ndx columnIndexes
+	firstLine := true
+	for {
+		records, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrParse, err)
+		}
+
+		if firstLine {
+			firstLine = false
+			if opts.Strict {
+				if unexpected := unexpectedColumns(records, opts); len(unexpected) > 0 {
+					return nil, fmt.Errorf("%w: the export has unexpected columns \"%s\"", ErrValidation, strings.Join(unexpected, "\", \""))
+				}
+			}
+			ndx = resolveColumns(records, opts)
+			backlog.HasSprints = ndx.sprint >= 0
+			backlog.HasAssignees = ndx.assignee >= 0
+			continue
+		}
+		if opts.interrupted() {
+			return nil, fmt.Errorf("%w: stopped while reading row %d", ErrInterrupted, backlog.RowsProcessed+1)
+		}
+		backlog.RowsProcessed++
+		line, _ := r.FieldPos(0)
+		if opts.MaxRows > 0 && backlog.RowsProcessed > opts.MaxRows {
+			return nil, fmt.Errorf("%w: the limit of %d rows was exceeded at row %d on line %d", ErrRowLimit, opts.MaxRows, backlog.RowsProcessed, line)
+		}
+		if opts.Verbose && opts.ProgressInterval > 0 && backlog.RowsProcessed%opts.ProgressInterval == 0 {
+			infof(opts, "processed %d rows...", backlog.RowsProcessed)
+		}
+
+		// Drop excluded items entirely
+		if opts.ExcludeKeys[records[ndx.issueID]] || opts.ExcludeKeys[records[ndx.issueKey]] {
+			continue
+		}
+
+		// See if the backlog item already exists
+		existingItem, ok := backlogMap[records[ndx.issueKey]]
+
+		// An issue id is unique to a single issue key, so an id seen with two different keys is an error in the
+		// data, such as a bad merge or the near identically named "Issue id" and "Issue key" columns having been
+		// swapped, rather than a repeated record.  The first record is kept
+		if ok && existingItem.id != "" && existingItem.id != records[ndx.issueID] {
+			errorf(opts, warnIDCollision, records[ndx.issueID], line, "Issue id \"%s\" is used by both \"%s\" and \"%s\" so \"%s\" is ignored; the \"%s\" and \"%s\" fields may be swapped", records[ndx.issueKey], existingItem.id, records[ndx.issueID], records[ndx.issueID], fieldIssueKey, fieldIssueID)
+			continue
+		}
+
+		// If backlog item already exists with the same id and key and has been read from a record of its own,
+		// rather than being a placeholder for a parent, then we are encountering a duplicate record which we will
+		// ignore whether or not children have been found for it
+		if ok && existingItem.id != "" {
+			warnf(opts, warnDuplicate, records[ndx.issueID], line, "Encountered an unexpected duplicate item: \"%s\"", records[ndx.issueID])
+			continue
+		}
+
+		// Transformations
+		var points float64
+		var opened time.Time
+		var closed time.Time
+		invalidPoints := false
+		populated := 0
+		for _, pointsNdx := range ndx.points {
+			value := optionalField(records, pointsNdx)
+			if value == "" {
+				continue
+			}
+			populated++
+			fieldPoints, err := parsePoints(value, opts)
+			if err != nil {
+				warnf(opts, warnBadPoints, records[ndx.issueID], line, "Unable to convert %s's story points of \"%s\" to an integer", records[ndx.issueID], value)
+				invalidPoints = true
+				continue
+			}
+			points += fieldPoints
+		}
+		if invalidPoints {
+			points = 0
+		}
+		if populated > 1 {
+			warnf(opts, warnAmbiguousPoints, records[ndx.issueID], line, "%s has story points in %d fields which have been summed", records[ndx.issueID], populated)
+		}
+		if records[ndx.created] != "" {
+			opened, err = parseJiraDate(records[ndx.created], opts)
+			if err != nil {
+				warnf(opts, warnBadCreated, records[ndx.issueID], line, "Unable to reformat %s's creation date of \"%s\"", records[ndx.issueID], records[ndx.created])
+			}
+		}
+		if records[ndx.resolved] != "" {
+			closed, err = parseJiraDate(records[ndx.resolved], opts)
+			if err != nil {
+				warnf(opts, warnBadResolved, records[ndx.issueID], line, "Unable to reformat %s's resolution date of \"%s\"", records[ndx.issueID], records[ndx.resolved])
+			}
+		}
+		resolution := optionalField(records, ndx.resolution)
+		if resolution != "" && closed.Equal(time.Time{}) {
+			updated := optionalField(records, ndx.updated)
+			if updated == "" {
+				warnf(opts, warnBadResolved, records[ndx.issueID], line, "%s is resolved as \"%s\" but has neither a resolution nor an updated date", records[ndx.issueID], resolution)
+			} else {
+				closed, err = parseJiraDate(updated, opts)
+				if err != nil {
+					warnf(opts, warnBadResolved, records[ndx.issueID], line, "Unable to reformat %s's updated date of \"%s\"", records[ndx.issueID], updated)
+				}
+			}
+		}
+		status := records[ndx.status]
+		if opts.AssumeClosed && closed.Equal(time.Time{}) && opts.isDoneStatus(status) {
+			closed = opts.runDate()
+			warnf(opts, warnAssumedClosed, records[ndx.issueID], line, "%s has a status of \"%s\" but no resolved date so is assumed closed on %s", records[ndx.issueID], status, closed.Format(isoDate))
+		}
+
+		// When the done statuses are given a resolved item only counts as closed while it is in one of them
+		if len(opts.DoneStatuses) > 0 && !opts.isDoneStatus(status) {
+			closed = time.Time{}
+		}
+		if !opened.Equal(time.Time{}) && !closed.Equal(time.Time{}) && closed.Before(opened) {
+			warnf(opts, warnInvertedDates, records[ndx.issueID], line, "%s was resolved on %s before it was created on %s", records[ndx.issueID], closed.Format(isoDate), opened.Format(isoDate))
+			switch opts.FixDates {
+			case FixDatesSwap:
+				opened, closed = closed, opened
+			case FixDatesDrop:
+				closed = time.Time{}
+			}
+		}
+
+		// Read the remaining estimate and the date it was last updated for counting partial progress
+		var remaining float64
+		var updated time.Time
+		hasRemaining := false
+		if value := optionalField(records, ndx.remaining); value != "" {
+			if opts.DecimalComma {
+				value = strings.Replace(value, ",", ".", -1)
+			}
+			remaining, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				warnf(opts, warnBadPoints, records[ndx.issueID], line, "Unable to convert %s's remaining estimate of \"%s\" to a number", records[ndx.issueID], value)
+			} else if value := optionalField(records, ndx.updated); value != "" {
+				updated, err = parseJiraDate(value, opts)
+				if err != nil {
+					warnf(opts, warnBadResolved, records[ndx.issueID], line, "Unable to reformat %s's updated date of \"%s\"", records[ndx.issueID], value)
+				} else {
+					hasRemaining = true
+				}
+			}
+		}
+
+		// Look at the backlog as it stood at the end of the as-of date, leaving out items not yet created and
+		// treating items not yet resolved as still open
+		if !opts.AsOf.IsZero() {
+			if !opened.Before(opts.dayAfterRunDate(opened.Location())) {
+				continue
+			}
+			if !closed.Before(opts.dayAfterRunDate(closed.Location())) {
+				closed = time.Time{}
+			}
+			if !updated.Before(opts.dayAfterRunDate(updated.Location())) {
+				hasRemaining = false
+			}
+		}
+
+		// Dates after the run date come from misconfigured automation and would stretch the totals out into the
+		// future, so warn about them and drop the item when asked to
+		if inFuture(opened, opts) || inFuture(closed, opts) {
+			future := opened
+			if !inFuture(opened, opts) {
+				future = closed
+			}
+			warnf(opts, warnFutureDate, records[ndx.issueID], line, "%s is dated %s which is after %s", records[ndx.issueID], future.Format(isoDate), opts.runDate().Format(isoDate))
+			if opts.DropFuture {
+				continue
+			}
+		}
+
+		// An item resolved before the run date but now in a reopen status has been reopened since
+		reopened := !closed.Equal(time.Time{}) && closed.Before(opts.runDate()) && opts.isReopenStatus(status)
+
+		// An item cannot be its own parent
+		parentKey := records[ndx.parentKey]
+		if parentKey != "" && (parentKey == records[ndx.issueKey] || parentKey == records[ndx.issueID]) {
+			warnf(opts, warnSelfParent, records[ndx.issueID], line, "%s references itself as its parent so is treated as having no parent", records[ndx.issueID])
+			parentKey = ""
+		}
+
+		// Having dealt with an unexpected duplicate record above, if the backlog item already exists at this
+		// point then it was a placeholder created when we encountered the child before the parent.  In this case,
+		// we will update everything preserving the hasChildren value and ignoring its story points.  Otherwise, we
+		// will add the completley new item to the map
+		if ok {
+			backlogMap[records[ndx.issueKey]] = backlogItem{
+				itemType:      records[ndx.issueType],
+				id:            records[ndx.issueID],
+				status:        status,
+				parent:        parentKey,
+				hasChildren:   true,
+				opened:        opened,
+				closed:        closed,
+				estimate:      points,
+				tags:          joinFields(records, ndx.labels),
+				sprint:        optionalField(records, ndx.sprint),
+				assignee:      optionalField(records, ndx.assignee),
+				project:       opts.Project,
+				resolution:    resolution,
+				group:         optionalField(records, ndx.group),
+				invalidPoints: invalidPoints,
+				remaining:     remaining,
+				hasRemaining:  hasRemaining,
+				updated:       updated,
+				reopened:      reopened,
+			}
+		} else {
+			backlogMap[records[ndx.issueKey]] = backlogItem{
+				itemType:      records[ndx.issueType],
+				id:            records[ndx.issueID],
+				status:        status,
+				parent:        parentKey,
+				hasChildren:   false,
+				opened:        opened,
+				closed:        closed,
+				points:        points,
+				estimate:      points,
+				tags:          joinFields(records, ndx.labels),
+				sprint:        optionalField(records, ndx.sprint),
+				assignee:      optionalField(records, ndx.assignee),
+				project:       opts.Project,
+				resolution:    resolution,
+				group:         optionalField(records, ndx.group),
+				invalidPoints: invalidPoints,
+				remaining:     remaining,
+				hasRemaining:  hasRemaining,
+				updated:       updated,
+				reopened:      reopened,
+			}
+		}
+
+		zeroParentPoints(backlogMap, records[ndx.issueKey], parentKey)
+	}
+
+	resolveParentIDs(backlogMap, opts)
+	warnCircularParents(backlogMap, opts)
+	if opts.RollupLabel != "" {
+		rollupLabeled(backlogMap, opts.RollupLabel)
+	}
+	if opts.LeafLevel == LeafLevelStory {
+		mergeSubtasks(backlogMap)
+	}
+	if opts.PointsLevel == PointsLevelLowestPointed {
+		countLowestPointed(backlogMap)
+	}
+	if opts.InheritPoints {
+		inheritParentPoints(backlogMap)
+	}
+	checkSuspectPoints(backlogMap, opts)
+	if opts.MinPoints > 0 {
+		backlog.dropBelowMinPoints(opts.MinPoints)
+	}
+
+	return backlog, nil
+}
+
+// Drop the pointed leaf items carrying fewer points than the minimum, setting them aside for their audit.
+// Unpointed items are kept so that they still show up in the no points audit
+func (backlog *Backlog) dropBelowMinPoints(minPoints float64) {
+	for key, item := range backlog.items {
+		if item.hasChildren || item.points <= 0 || item.points >= minPoints {
+			continue
+		}
+		backlog.belowMin = append(backlog.belowMin, item)
+		delete(backlog.items, key)
+	}
+}
+
+// A backlog item as written when dumping the parsed backlog for debugging
+type itemJSON struct {
+	Type          string  `json:"type"`
+	ID            string  `json:"id"`
+	Status        string  `json:"status,omitempty"`
+	Parent        string  `json:"parent,omitempty"`
+	HasChildren   bool    `json:"hasChildren"`
+	Opened        string  `json:"opened,omitempty"`
+	Closed        string  `json:"closed,omitempty"`
+	Points        float64 `json:"points"`
+	Estimate      float64 `json:"estimate"`
+	Labels        string  `json:"labels,omitempty"`
+	Sprint        string  `json:"sprint,omitempty"`
+	Assignee      string  `json:"assignee,omitempty"`
+	Project       string  `json:"project,omitempty"`
+	Resolution    string  `json:"resolution,omitempty"`
+	Group         string  `json:"group,omitempty"`
+	InvalidPoints bool    `json:"invalidPoints"`
+}
+
+// WriteItemsJSON writes every parsed backlog item, including parent placeholders, as JSON keyed by its unique
+// record ID.  This shows where the parent walk and duplicate handling left each item before aggregation
+func (backlog *Backlog) WriteItemsJSON(w io.Writer) error {
+	items := make(map[string]itemJSON, len(backlog.items))
+	for key, item := range backlog.items {
+		dumped := itemJSON{
+			Type:          item.itemType,
+			ID:            item.id,
+			Status:        item.status,
+			Parent:        item.parent,
+			HasChildren:   item.hasChildren,
+			Points:        item.points,
+			Estimate:      item.estimate,
+			Labels:        item.tags,
+			Sprint:        item.sprint,
+			Assignee:      item.assignee,
+			Project:       item.project,
+			Resolution:    item.resolution,
+			Group:         item.group,
+			InvalidPoints: item.invalidPoints,
+		}
+		if !item.opened.IsZero() {
+			dumped.Opened = item.opened.Format(time.RFC3339)
+		}
+		if !item.closed.IsZero() {
+			dumped.Closed = item.closed.Format(time.RFC3339)
+		}
+		items[key] = dumped
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(items)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	return nil
+}
+
+// Points of an open item already done according to its remaining estimate.  These are counted as closed on
+// the date the item was last updated
+func (item backlogItem) partialPoints() float64 {
+	if !item.hasRemaining || !item.closed.Equal(time.Time{}) || item.remaining >= item.points {
+		return 0
+	}
+	if item.remaining <= 0 {
+		return item.points
+	}
+	return item.points - item.remaining
+}
+
+// Partition the backlog by the value of the grouping column.  Items without a value go into an "(ungrouped)"
+// group
+func (backlog *Backlog) partition() map[string]*Backlog {
+	return backlog.partitionBy(func(item backlogItem) string {
+		return item.group
+	})
+}
+
+// Partition the backlog by the value an item is keyed on.  Items without a value go into an "(ungrouped)" group
+func (backlog *Backlog) partitionBy(keyOf func(item backlogItem) string) map[string]*Backlog {
+	const ungrouped = "(ungrouped)"
+	groups := make(map[string]*Backlog)
+	for key, item := range backlog.items {
+		group := keyOf(item)
+		if group == "" {
+			group = ungrouped
+		}
+		groupBacklog, ok := groups[group]
+		if !ok {
+			groupBacklog = &Backlog{
+				HasSprints: backlog.HasSprints,
+				items:      make(map[string]backlogItem),
+			}
+			groups[group] = groupBacklog
+		}
+		groupBacklog.items[key] = item
+	}
+	return groups
+}
+
+// Sum the points of each parent's leaf descendants keyed by the parent's unique record ID
+func (backlog *Backlog) rolledUpPoints() map[string]float64 {
+	rollup := make(map[string]float64)
+	for key, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		visited := map[string]bool{key: true}
+		for parentKey := item.parent; parentKey != "" && !visited[parentKey]; parentKey = backlog.items[parentKey].parent {
+			visited[parentKey] = true
+			rollup[parentKey] += item.points
+		}
+	}
+	return rollup
+}
+
+// Zero out the points of every ancestor of the given child starting from its parent.  A parent already found
+// to have children had its own ancestors zeroed at the time, or will have once its row turns up, so the walk
+// stops there and each ancestor is only walked through once however many children it has.  The keys walked
+// are remembered so that a cycle in the hierarchy is not followed forever; cycles are reported once the whole
+// hierarchy is known
+func zeroParentPoints(backlogMap map[string]backlogItem, childKey string, parentKey string) {
+	walked := []string{childKey}
+	for parentKey != "" {
+
+		for _, key := range walked {
+			if key == parentKey {
+				return
+			}
+		}
+		walked = append(walked, parentKey)
+
+		parentItem, ok := backlogMap[parentKey]
+
+		// We have seen a child before we've seen the parent, so add a placeholder
+		// and move on.  The walk can stop here because when the parent's own row
+		// turns up it keeps the placeholder's hasChildren value, leaves its points
+		// at zero and walks on up to its own ancestors
+		if !ok {
+			backlogMap[parentKey] = backlogItem{
+				hasChildren: true,
+			}
+			return
+		}
+
+		// We have already been here through another child
+		if parentItem.hasChildren {
+			return
+		}
+
+		// We have a parent so make sure its story points are zero and that the
+		// indicator that it has children is set
+		parentItem.hasChildren = true
+		parentItem.points = 0
+		backlogMap[parentKey] = parentItem
+
+		// And walk up the chain to its parent if one exists
+		parentKey = parentItem.parent
+	}
+}
+
+// Report each cycle in the hierarchy once, starting from the item in it with the lowest record ID so that the
+// report does not depend on the order the items are visited in
+func warnCircularParents(backlogMap map[string]backlogItem, opts Options) {
+	const unvisited, onPath, done = 0, 1, 2
+	state := make(map[string]int, len(backlogMap))
+	for key := range backlogMap {
+		var path []string
+		current := key
+		for current != "" && state[current] == unvisited {
+			state[current] = onPath
+			path = append(path, current)
+			current = backlogMap[current].parent
+		}
+		if current != "" && state[current] == onPath {
+			var cycle []string
+			for i, walkedKey := range path {
+				if walkedKey == current {
+					cycle = path[i:]
+					break
+				}
+			}
+			first := 0
+			for i := range cycle {
+				if cycle[i] < cycle[first] {
+					first = i
+				}
+			}
+			var ids []string
+			for i := 0; i <= len(cycle); i++ {
+				ids = append(ids, backlogMap[cycle[(first+i)%len(cycle)]].id)
+			}
+			warnf(opts, warnCircularParent, ids[0], 0, "Encountered a circular parent reference: %s", strings.Join(ids, " -> "))
+		}
+		for _, walkedKey := range path {
+			state[walkedKey] = done
+		}
+	}
+}
+
+// Link items whose parent field, such as JIRA's classic "Epic Link", holds the parent's issue key rather than
+// its record ID to the parent's record.  Such links are left pointing at placeholders by the parent walk, as
+// items are keyed by record ID, so once every record is known each placeholder named for the key of a real
+// item is dropped and the walk repeated from its children
+func resolveParentIDs(backlogMap map[string]backlogItem, opts Options) {
+	keysByID := make(map[string]string)
+	for key, item := range backlogMap {
+		if item.id != "" {
+			keysByID[item.id] = key
+		}
+	}
+	relinked := make(map[string]string)
+	for key, item := range backlogMap {
+		if item.parent == "" || backlogMap[item.parent].id != "" {
+			continue
+		}
+		if parentKey, ok := keysByID[item.parent]; ok && parentKey != key {
+			relinked[key] = parentKey
+		}
+	}
+	for key, parentKey := range relinked {
+		item := backlogMap[key]
+		delete(backlogMap, item.parent)
+		item.parent = parentKey
+		backlogMap[key] = item
+	}
+	for key, parentKey := range relinked {
+		zeroParentPoints(backlogMap, key, parentKey)
+	}
+}
+
+// Warn about the leaf items whose story points are negative or above the maximum, which are likely mistakes in
+// entering them, dropping them when asked to.  The points of parents are not counted so they are left alone.
+// This has to wait until all the parent/child links are known
+func checkSuspectPoints(backlogMap map[string]backlogItem, opts Options) {
+	for key, item := range backlogMap {
+		if item.hasChildren || (item.points >= 0 && item.points <= opts.MaxPoints) {
+			continue
+		}
+		warnf(opts, warnSuspectPoints, item.id, 0, "%s has suspect story points of %g", item.id, item.points)
+		if opts.SkipSuspectPoints {
+			delete(backlogMap, key)
+		}
+	}
+}
+
+// Distribute each pointed parent's estimate evenly across those of its direct children which are leaves
+// without points of their own.  This has to wait until all the parent/child links are known
+func inheritParentPoints(backlogMap map[string]backlogItem) {
+	unpointedChildren := make(map[string][]string)
+	for key, item := range backlogMap {
+		if item.parent != "" && !item.hasChildren && item.points == 0 {
+			unpointedChildren[item.parent] = append(unpointedChildren[item.parent], key)
+		}
+	}
+	for parentKey, childKeys := range unpointedChildren {
+		parentItem := backlogMap[parentKey]
+		if parentItem.estimate <= 0 || !parentItem.hasChildren {
+			continue
+		}
+		share := parentItem.estimate / float64(len(childKeys))
+		for _, childKey := range childKeys {
+			childItem := backlogMap[childKey]
+			childItem.points = share
+			backlogMap[childKey] = childItem
+		}
+	}
+}
+
+// Treat each parent carrying the rollup label as a leaf counting its own points, leaving all its descendants
+// out of the backlog.  A labeled parent beneath another is dropped along with the rest of the outer parent's
+// descendants.  This has to wait until all the parent/child links are known
+func rollupLabeled(backlogMap map[string]backlogItem, label string) {
+	rollups := make(map[string]bool)
+	for key, item := range backlogMap {
+		if item.hasChildren && item.id != "" && hasLabel(item.tags, label) {
+			rollups[key] = true
+		}
+	}
+	if len(rollups) == 0 {
+		return
+	}
+	var rolledUp []string
+	for key, item := range backlogMap {
+		visited := map[string]bool{key: true}
+		for parentKey := item.parent; parentKey != "" && !visited[parentKey]; parentKey = backlogMap[parentKey].parent {
+			visited[parentKey] = true
+			if rollups[parentKey] {
+				rolledUp = append(rolledUp, key)
+				break
+			}
+		}
+	}
+	for _, key := range rolledUp {
+		delete(backlogMap, key)
+	}
+	for key := range rollups {
+		item, ok := backlogMap[key]
+		if !ok {
+			continue
+		}
+		item.hasChildren = false
+		item.points = item.estimate
+		backlogMap[key] = item
+	}
+}
+
+// Whether the labels of an item include the given one
+func hasLabel(tags string, label string) bool {
+	for _, tag := range strings.Fields(tags) {
+		if tag == label {
+			return true
+		}
+	}
+	return false
+}
+
+// Treat each parent whose children are all sub-tasks as a leaf counting its own points, leaving its sub-tasks
+// out of the backlog.  This has to wait until all the parent/child links are known
+func mergeSubtasks(backlogMap map[string]backlogItem) {
+	allSubtasks := make(map[string]bool)
+	for _, item := range backlogMap {
+		if item.parent == "" {
+			continue
+		}
+		subtasks, ok := allSubtasks[item.parent]
+		allSubtasks[item.parent] = (subtasks || !ok) && isSubtask(item.itemType)
+	}
+	for parentKey, subtasks := range allSubtasks {
+		parentItem, ok := backlogMap[parentKey]
+		if !subtasks || !ok || parentItem.id == "" {
+			delete(allSubtasks, parentKey)
+			continue
+		}
+		parentItem.hasChildren = false
+		parentItem.points = parentItem.estimate
+		backlogMap[parentKey] = parentItem
+	}
+	for key, item := range backlogMap {
+		if allSubtasks[item.parent] {
+			delete(backlogMap, key)
+		}
+	}
+}
+
+// Whether a date falls on a day after the run date, comparing calendar days in the date's own time zone
+func inFuture(date time.Time, opts Options) bool {
+	return !date.IsZero() && !date.Before(opts.dayAfterRunDate(date.Location()))
+}
+
+// Whether a status is one of the terminal statuses JIRA uses for finished work by default
+func isDoneStatus(status string) bool {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "done", "closed", "resolved":
+		return true
+	}
+	return false
+}
+
+// Whether an issue type is a sub-task, which JIRA spells in a few different ways
+func isSubtask(itemType string) bool {
+	switch strings.ToLower(strings.TrimSpace(itemType)) {
+	case "sub-task", "subtask", "sub task":
+		return true
+	}
+	return false
+}
+
+// Count the points of each pointed parent none of whose descendants are pointed by treating it as the leaf of
+// its part of the hierarchy.  Parents with pointed descendants keep their points zeroed so that nothing is
+// counted twice.  This has to wait until all the parent/child links are known
+func countLowestPointed(backlogMap map[string]backlogItem) {
+	pointedDescendants := make(map[string]bool)
+	for key, item := range backlogMap {
+		if item.estimate <= 0 || item.invalidPoints {
+			continue
+		}
+		visited := map[string]bool{key: true}
+		for parentKey := item.parent; parentKey != "" && !visited[parentKey]; parentKey = backlogMap[parentKey].parent {
+			visited[parentKey] = true
+			pointedDescendants[parentKey] = true
+		}
+	}
+	for key, item := range backlogMap {
+		if !item.hasChildren || item.estimate <= 0 || item.invalidPoints || pointedDescendants[key] {
+			continue
+		}
+		item.hasChildren = false
+		item.points = item.estimate
+		backlogMap[key] = item
+	}
+}
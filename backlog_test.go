@@ -0,0 +1,712 @@
+package burnup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Header of the exports used in the tests, naming the columns as JIRA does
+const testHeader = "Issue key,Issue id,Issue Type,Status,Created,Resolved,Labels,Custom field (Story point estimate),Parent\n"
+
+// Options the tests parse with
+func testOptions() Options {
+	return DefaultOptions()
+}
+
+// Parse an export given as its data rows beneath the test header
+func parseTestBacklog(t *testing.T, rows string, opts Options) *Backlog {
+	t.Helper()
+	backlog, err := ParseBacklog(strings.NewReader(testHeader+rows), opts)
+	if err != nil {
+		t.Fatalf("ParseBacklog() error = %v", err)
+	}
+	return backlog
+}
+
+// Capture what is logged for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logged bytes.Buffer
+	writer, flags := log.Writer(), log.Flags()
+	log.SetOutput(&logged)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+	return &logged
+}
+
+// Look up a parsed item by its issue key
+func itemByID(backlog *Backlog, id string) (backlogItem, bool) {
+	for _, item := range backlog.items {
+		if item.id == id {
+			return item, true
+		}
+	}
+	return backlogItem{}, false
+}
+
+func TestSuspectPoints(t *testing.T) {
+	rows := "P-1,1,Epic,To Do,01/Mar/24 09:00 AM,,,8,\n" +
+		"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,2,1\n" +
+		"P-3,3,Story,To Do,01/Mar/24 09:00 AM,,,10,\n" +
+		"P-4,4,Story,To Do,01/Mar/24 09:00 AM,,,-1,\n"
+	tests := []struct {
+		name     string
+		skip     bool
+		wantKept []string
+		wantGone []string
+	}{
+		{"warn only", false, []string{"P-1", "P-2", "P-3", "P-4"}, nil},
+		{"skip", true, []string{"P-1", "P-2"}, []string{"P-3", "P-4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			opts := testOptions()
+			opts.MaxPoints = 4
+			opts.SkipSuspectPoints = tt.skip
+			backlog := parseTestBacklog(t, rows, opts)
+			for _, id := range tt.wantKept {
+				if _, ok := itemByID(backlog, id); !ok {
+					t.Errorf("%s was dropped", id)
+				}
+			}
+			for _, id := range tt.wantGone {
+				if _, ok := itemByID(backlog, id); ok {
+					t.Errorf("%s was kept", id)
+				}
+			}
+			if epic, _ := itemByID(backlog, "P-1"); !epic.hasChildren {
+				t.Errorf("P-1 has lost its children")
+			}
+			if got := strings.Count(logged.String(), "suspect story points"); got != 2 {
+				t.Errorf("suspect points warnings = %d, want 2 for the leaves alone", got)
+			}
+		})
+	}
+}
+
+func TestHeaderMatching(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"as exported", testHeader},
+		{"upper case", strings.ToUpper(testHeader)},
+		{"padded", " Issue Key , Issue ID ,Issue type,STATUS,created,Resolved , labels,Custom Field (Story Point Estimate), parent\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			backlog, err := ParseBacklog(strings.NewReader(tt.header+testRows), testOptions())
+			if err != nil {
+				t.Fatalf("ParseBacklog() error = %v", err)
+			}
+			story, ok := itemByID(backlog, "P-2")
+			if !ok {
+				t.Fatalf("P-2 was not parsed")
+			}
+			if story.points != 3 || story.parent != "1" || story.itemType != "Story" || story.closed.IsZero() {
+				t.Errorf("P-2 = %+v, want a closed story of 3 points under 1", story)
+			}
+		})
+	}
+}
+
+func TestInvertedDates(t *testing.T) {
+	const rows = "P-1,1,Story,Done,05/Mar/24 09:00 AM,02/Mar/24 09:00 AM,,3,\n"
+	tests := []struct {
+		fix        string
+		wantOpened string
+		wantClosed string
+	}{
+		{FixDatesNone, "2024-03-05", "2024-03-02"},
+		{FixDatesSwap, "2024-03-02", "2024-03-05"},
+		{FixDatesDrop, "2024-03-05", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fix, func(t *testing.T) {
+			logged := captureLog(t)
+			opts := testOptions()
+			opts.FixDates = tt.fix
+			item, _ := itemByID(parseTestBacklog(t, rows, opts), "P-1")
+			if got := formatDate(item.opened); got != tt.wantOpened {
+				t.Errorf("opened = %s, want %s", got, tt.wantOpened)
+			}
+			if got := formatDate(item.closed); got != tt.wantClosed {
+				t.Errorf("closed = %s, want %s", got, tt.wantClosed)
+			}
+			if got := strings.Count(logged.String(), "P-1 was resolved on 2024-03-02 before it was created on 2024-03-05"); got != 1 {
+				t.Errorf("inverted date warnings = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestBadDateMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		want string
+	}{
+		{"created", "P-1,1,Story,Done,2024/13/45,04/Mar/24 09:00 AM,,3,\n", "P-1's creation date of \"2024/13/45\""},
+		{"resolved", "P-1,1,Story,Done,02/Mar/24 09:00 AM,yesterday,,3,\n", "P-1's resolution date of \"yesterday\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			_, err := ParseBacklog(strings.NewReader(testHeader+tt.row), testOptions())
+			if err != nil {
+				t.Fatalf("ParseBacklog() error = %v", err)
+			}
+			if !strings.Contains(logged.String(), tt.want) {
+				t.Errorf("logged %q, want it to name %s", logged.String(), tt.want)
+			}
+			if strings.Contains(logged.String(), "\"3\"") {
+				t.Errorf("logged %q, which gives the story points rather than the date", logged.String())
+			}
+		})
+	}
+}
+
+func TestCircularParents(t *testing.T) {
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{"two items", "P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,2\n" +
+			"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,5,1\n"},
+		{"three items", "P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,3\n" +
+			"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,5,1\n" +
+			"P-3,3,Story,To Do,01/Mar/24 09:00 AM,,,8,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			opts := testOptions()
+			done := make(chan *Backlog)
+			go func() {
+				backlog, _ := ParseBacklog(strings.NewReader(testHeader+tt.rows), opts)
+				done <- backlog
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("ParseBacklog() did not return on a circular hierarchy")
+			}
+			if got := strings.Count(logged.String(), "circular parent reference"); got != 1 {
+				t.Errorf("circular parent warnings = %d, want 1", got)
+			}
+			if !strings.Contains(logged.String(), "P-1 -> ") {
+				t.Errorf("logged %q, want the cycle listed from P-1", logged.String())
+			}
+		})
+	}
+}
+
+func TestParsePoints(t *testing.T) {
+	tests := []struct {
+		value        string
+		decimalComma bool
+		want         float64
+		wantErr      bool
+	}{
+		{"5", false, 5, false},
+		{"5.0", false, 5, false},
+		{"5,0", false, 0, true},
+		{"5", true, 5, false},
+		{"5.0", true, 5, false},
+		{"5,0", true, 5, false},
+		{"2,5", true, 2.5, false},
+		{"five", true, 0, true},
+	}
+	for _, tt := range tests {
+		opts := testOptions()
+		opts.DecimalComma = tt.decimalComma
+		got, err := parsePoints(tt.value, opts)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePoints(%q) with decimal comma %v = %g, %v, want %g with error %v", tt.value, tt.decimalComma, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestInvalidPointsAudited(t *testing.T) {
+	captureLog(t)
+	const rows = "P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,\"5,0\",\n" +
+		"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,5.0,\n"
+	opts := testOptions()
+	backlog := parseTestBacklog(t, rows, opts)
+	unpointed := unpointedItems(backlog)
+	if len(unpointed) != 1 || unpointed[0].id != "P-1" || !unpointed[0].invalidPoints {
+		t.Errorf("no points audit = %+v, want P-1 flagged as invalid", unpointed)
+	}
+	if got := opts.Warnings.counts[warnBadPoints]; got != 1 {
+		t.Errorf("bad points warnings = %d, want 1", got)
+	}
+}
+
+func TestAsOfInInstanceZone(t *testing.T) {
+	const rows = "P-1,1,Story,Done,01/Mar/24 09:00 AM,01/Mar/24 22:00 PM,,3,\n" +
+		"P-2,2,Story,To Do,01/Mar/24 23:00 PM,,,5,\n" +
+		"P-3,3,Story,Done,01/Mar/24 09:00 AM,02/Mar/24 00:30 AM,,2,\n" +
+		"P-4,4,Story,To Do,02/Mar/24 00:30 AM,,,8,\n"
+	captureLog(t)
+	opts := testOptions()
+	opts.AsOf = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	opts.InstanceZone = time.FixedZone("CST", -6*60*60)
+	backlog := parseTestBacklog(t, rows, opts)
+	tests := []struct {
+		id         string
+		wantKept   bool
+		wantClosed bool
+	}{
+		{"P-1", true, true},
+		{"P-2", true, false},
+		{"P-3", true, false},
+		{"P-4", false, false},
+	}
+	for _, tt := range tests {
+		item, ok := itemByID(backlog, tt.id)
+		if ok != tt.wantKept {
+			t.Errorf("%s kept = %v, want %v", tt.id, ok, tt.wantKept)
+			continue
+		}
+		if closed := !item.closed.IsZero(); ok && closed != tt.wantClosed {
+			t.Errorf("%s closed = %v, want %v", tt.id, closed, tt.wantClosed)
+		}
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	tests := []struct {
+		maxRows int
+		wantErr bool
+	}{
+		{0, false},
+		{5, false},
+		{4, true},
+		{1, true},
+	}
+	for _, tt := range tests {
+		captureLog(t)
+		opts := testOptions()
+		opts.MaxRows = tt.maxRows
+		_, err := ParseBacklog(strings.NewReader(testHeader+testRows), opts)
+		if got := errors.Is(err, ErrRowLimit); got != tt.wantErr {
+			t.Errorf("MaxRows %d: error = %v, want the row limit error %v", tt.maxRows, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr && !strings.Contains(err.Error(), fmt.Sprintf("limit of %d rows was exceeded at row %d", tt.maxRows, tt.maxRows+1)) {
+			t.Errorf("MaxRows %d: error = %v, want it to give the limit and the row it stopped at", tt.maxRows, err)
+		}
+	}
+}
+
+func TestParentPointsZeroedInAnyOrder(t *testing.T) {
+	grandparent := "G-1,1,Epic,To Do,01/Mar/24 09:00 AM,,,8,\n"
+	parent := "P-1,2,Story,To Do,01/Mar/24 09:00 AM,,,5,1\n"
+	child := "C-1,3,Sub-task,To Do,01/Mar/24 09:00 AM,,,2,2\n"
+	orders := map[string][]string{
+		"child, parent, grandparent": {child, parent, grandparent},
+		"child, grandparent, parent": {child, grandparent, parent},
+		"parent, child, grandparent": {parent, child, grandparent},
+		"parent, grandparent, child": {parent, grandparent, child},
+		"grandparent, child, parent": {grandparent, child, parent},
+		"grandparent, parent, child": {grandparent, parent, child},
+	}
+	for name, rows := range orders {
+		t.Run(name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			backlog := parseTestBacklog(t, strings.Join(rows, ""), opts)
+			if len(backlog.items) != 3 {
+				t.Errorf("parsed %d items, want 3 without placeholders left over", len(backlog.items))
+			}
+			for _, id := range []string{"G-1", "P-1"} {
+				item, _ := itemByID(backlog, id)
+				if !item.hasChildren || item.points != 0 {
+					t.Errorf("%s has children %v and %g points, want children and 0 points", id, item.hasChildren, item.points)
+				}
+			}
+			if totals := ComputeTotals(backlog, opts); totals.TotalPoints != 2 {
+				t.Errorf("total points = %g, want the 2 points of C-1 alone", totals.TotalPoints)
+			}
+		})
+	}
+}
+
+func TestRepeatedLabelColumns(t *testing.T) {
+	const header = "Issue key,Issue id,Issue Type,Status,Created,Resolved,Labels,Labels,Custom field (Story point estimate),Labels,Parent\n"
+	tests := []struct {
+		row  string
+		want string
+	}{
+		{"P-1,1,Story,To Do,01/Mar/24 09:00 AM,,ui,backend,3,urgent,\n", "ui backend urgent"},
+		{"P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,backend,3,,\n", "backend"},
+		{"P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,,3,,\n", ""},
+	}
+	for _, tt := range tests {
+		captureLog(t)
+		backlog, err := ParseBacklog(strings.NewReader(header+tt.row), testOptions())
+		if err != nil {
+			t.Fatalf("ParseBacklog() error = %v", err)
+		}
+		item, _ := itemByID(backlog, "P-1")
+		if item.tags != tt.want || item.points != 3 {
+			t.Errorf("labels %q and %g points, want labels %q and 3 points", item.tags, item.points, tt.want)
+		}
+	}
+}
+
+func TestSelfParent(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+	}{
+		{"by record id", "P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,1\n"},
+		{"by issue key", "P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,P-1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			opts := testOptions()
+			backlog := parseTestBacklog(t, tt.row, opts)
+			item, _ := itemByID(backlog, "P-1")
+			if item.parent != "" || item.hasChildren || item.points != 3 || len(backlog.items) != 1 {
+				t.Errorf("P-1 = %+v, want a leaf of 3 points without a parent", item)
+			}
+			if got := opts.Warnings.counts[warnSelfParent]; got != 1 {
+				t.Errorf("self parent warnings = %d, want 1", got)
+			}
+			if opts.Warnings.counts[warnCircularParent] != 0 {
+				t.Errorf("a self parent was also reported as a circular reference")
+			}
+			if !strings.Contains(logged.String(), "P-1 references itself as its parent") {
+				t.Errorf("logged %q, want it to name P-1 as its own parent", logged.String())
+			}
+		})
+	}
+}
+
+// Export of a chain of epics the given number of levels deep with the given number of stories under the
+// deepest, listing the stories before or after the epics
+func deepHierarchy(depth int, stories int, storiesFirst bool) string {
+	var epics, leaves strings.Builder
+	for i := 1; i <= depth; i++ {
+		parent := ""
+		if i > 1 {
+			parent = strconv.Itoa(i - 1)
+		}
+		fmt.Fprintf(&epics, "E-%d,%d,Epic,To Do,01/Mar/24 09:00 AM,,,8,%s\n", i, i, parent)
+	}
+	for i := 1; i <= stories; i++ {
+		fmt.Fprintf(&leaves, "S-%d,%d,Story,To Do,02/Mar/24 09:00 AM,,,3,%d\n", i, depth+i, depth)
+	}
+	if storiesFirst {
+		return testHeader + leaves.String() + epics.String()
+	}
+	return testHeader + epics.String() + leaves.String()
+}
+
+func BenchmarkParseBacklog(b *testing.B) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+	for _, bm := range []struct {
+		name         string
+		storiesFirst bool
+	}{
+		{"epics first", false},
+		{"stories first", true},
+	} {
+		export := deepHierarchy(500, 10000, bm.storiesFirst)
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := ParseBacklog(strings.NewReader(export), testOptions())
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFutureDates(t *testing.T) {
+	zone := time.FixedZone("CST", -6*60*60)
+	today := time.Now().In(zone).Format(jiraDate)
+	rows := "P-1,1,Story,To Do," + today + ",,,3,\n" +
+		"P-2,2,Story,To Do,01/Mar/60 09:00 AM,,,5,\n" +
+		"P-3,3,Story,Done,01/Mar/24 09:00 AM,02/Mar/60 09:00 AM,,2,\n"
+	tests := []struct {
+		dropFuture bool
+		wantKept   []string
+		wantGone   []string
+	}{
+		{false, []string{"P-1", "P-2", "P-3"}, nil},
+		{true, []string{"P-1"}, []string{"P-2", "P-3"}},
+	}
+	for _, tt := range tests {
+		captureLog(t)
+		opts := testOptions()
+		opts.InstanceZone = zone
+		opts.DropFuture = tt.dropFuture
+		backlog := parseTestBacklog(t, rows, opts)
+		for _, id := range tt.wantKept {
+			if _, ok := itemByID(backlog, id); !ok {
+				t.Errorf("drop future %v: %s was dropped", tt.dropFuture, id)
+			}
+		}
+		for _, id := range tt.wantGone {
+			if _, ok := itemByID(backlog, id); ok {
+				t.Errorf("drop future %v: %s was kept", tt.dropFuture, id)
+			}
+		}
+		if got := opts.Warnings.counts[warnFutureDate]; got != 2 {
+			t.Errorf("drop future %v: future date warnings = %d, want 2", tt.dropFuture, got)
+		}
+	}
+}
+
+func TestFutureDatesAsOf(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.AsOf = time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	opts.InstanceZone = time.FixedZone("CST", -6*60*60)
+	opts.DropFuture = true
+	backlog := parseTestBacklog(t, "P-1,1,Story,Done,01/Mar/24 09:00 AM,01/Mar/24 22:00 PM,,3,\n", opts)
+	item, ok := itemByID(backlog, "P-1")
+	if !ok || item.closed.IsZero() {
+		t.Errorf("P-1 = %+v, want it kept and closed on the as-of day", item)
+	}
+	if got := opts.Warnings.counts[warnFutureDate]; got != 0 {
+		t.Errorf("future date warnings = %d, want 0 for an item dated on the as-of day", got)
+	}
+	if inFuture(time.Date(2024, time.March, 1, 23, 59, 0, 0, opts.InstanceZone), opts) {
+		t.Errorf("late on the as-of day in the instance zone is in the future")
+	}
+	if !inFuture(time.Date(2024, time.March, 2, 0, 0, 0, 0, opts.InstanceZone), opts) {
+		t.Errorf("the day after the as-of day is not in the future")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	tests := []struct {
+		name           string
+		rows           string
+		wantPoints     float64
+		wantStatus     string
+		wantDuplicates int
+		wantCollisions int
+	}{
+		{
+			"true duplicate keeps the first",
+			"P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,\n" +
+				"P-1,1,Story,Done,01/Mar/24 09:00 AM,02/Mar/24 09:00 AM,,5,\n",
+			3, "To Do", 1, 0,
+		},
+		{
+			"duplicate parent keeps the first",
+			"P-1,1,Epic,To Do,01/Mar/24 09:00 AM,,,8,\n" +
+				"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,3,1\n" +
+				"P-1,1,Epic,Done,01/Mar/24 09:00 AM,02/Mar/24 09:00 AM,,13,\n",
+			0, "To Do", 1, 0,
+		},
+		{
+			"id shared by another key",
+			"P-1,1,Story,To Do,01/Mar/24 09:00 AM,,,3,\n" +
+				"Q-7,1,Story,Done,01/Mar/24 09:00 AM,02/Mar/24 09:00 AM,,5,\n",
+			3, "To Do", 0, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			backlog := parseTestBacklog(t, tt.rows, opts)
+			item := backlog.items["1"]
+			if item.id != "P-1" || item.points != tt.wantPoints || item.status != tt.wantStatus || !item.closed.IsZero() {
+				t.Errorf("item 1 = %+v, want the first record of P-1 with %g points", item, tt.wantPoints)
+			}
+			if got := opts.Warnings.counts[warnDuplicate]; got != tt.wantDuplicates {
+				t.Errorf("duplicate warnings = %d, want %d", got, tt.wantDuplicates)
+			}
+			if got := opts.Warnings.counts[warnIDCollision]; got != tt.wantCollisions {
+				t.Errorf("id collision errors = %d, want %d", got, tt.wantCollisions)
+			}
+		})
+	}
+}
+
+func TestDoneStatuses(t *testing.T) {
+	const rows = "P-1,1,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,3,\n" +
+		"P-2,2,Story,Reopened,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,5,\n" +
+		"P-3,3,Story,Shipped,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,2,\n" +
+		"P-4,4,Story,In Progress,01/Mar/24 09:00 AM,,,8,\n"
+	tests := []struct {
+		name       string
+		statuses   string
+		wantClosed []string
+	}{
+		{"resolved date alone", "", []string{"P-1", "P-2", "P-3"}},
+		{"done statuses", "done, shipped", []string{"P-1", "P-3"}},
+		{"single status", "Done", []string{"P-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			opts.AddDoneStatuses(tt.statuses)
+			backlog := parseTestBacklog(t, rows, opts)
+			closed := make(map[string]bool)
+			for _, id := range tt.wantClosed {
+				closed[id] = true
+			}
+			for _, id := range []string{"P-1", "P-2", "P-3", "P-4"} {
+				item, _ := itemByID(backlog, id)
+				if got := !item.closed.IsZero(); got != closed[id] {
+					t.Errorf("%s with status %q closed = %v, want %v", id, item.status, got, closed[id])
+				}
+			}
+			if item, _ := itemByID(backlog, "P-2"); item.status != "Reopened" {
+				t.Errorf("P-2 status = %q, want Reopened", item.status)
+			}
+		})
+	}
+}
+
+func TestParseJiraDate(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		value string
+		zone  *time.Location
+		want  time.Time
+	}{
+		{"02/Jan/24 03:04 AM", nil, time.Date(2024, time.January, 2, 3, 4, 0, 0, time.UTC)},
+		{"02/Jan/24 03:04 AM", chicago, time.Date(2024, time.January, 2, 3, 4, 0, 0, chicago)},
+		{"2024-01-02T15:04:05.000+0000", nil, time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05.000+0000", chicago, time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05-0600", nil, time.Date(2024, time.January, 2, 21, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04-0600", nil, time.Date(2024, time.January, 2, 21, 4, 0, 0, time.UTC)},
+		{"2024-01-02T15:04:05Z", nil, time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05.123456+05:30", nil, time.Date(2024, time.January, 2, 9, 34, 5, 123456000, time.UTC)},
+		{"2024-01-02T15:04+01:00", nil, time.Date(2024, time.January, 2, 14, 4, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		opts := testOptions()
+		opts.InstanceZone = tt.zone
+		got, err := parseJiraDate(tt.value, opts)
+		if err != nil {
+			t.Errorf("parseJiraDate(%q) error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseJiraDate(%q) in %v = %v, want %v", tt.value, tt.zone, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"2024-01-02", "2024-01-02T15:04:05", "2024-13-02T15:04:05Z", "Jan 2, 2024"} {
+		if _, err := parseJiraDate(value, testOptions()); err == nil {
+			t.Errorf("parseJiraDate(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestISODatesInBacklog(t *testing.T) {
+	captureLog(t)
+	backlog := parseTestBacklog(t, "P-1,1,Story,Done,2024-03-01T09:00:00.000+0000,2024-03-04T17:30:00.000+0000,,3,\n"+
+		"P-2,2,Story,Done,01/Mar/24 09:00 AM,2024-03-05T10:00:00Z,,5,\n", testOptions())
+	tests := []struct {
+		id             string
+		opened, closed time.Time
+	}{
+		{"P-1", time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, time.March, 4, 17, 30, 0, 0, time.UTC)},
+		{"P-2", time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		item, ok := itemByID(backlog, tt.id)
+		if !ok {
+			t.Fatalf("%s missing from the backlog", tt.id)
+		}
+		if !item.opened.Equal(tt.opened) || !item.closed.Equal(tt.closed) {
+			t.Errorf("%s opened %v closed %v, want %v and %v", tt.id, item.opened, item.closed, tt.opened, tt.closed)
+		}
+	}
+}
+
+func TestRollupLabel(t *testing.T) {
+	const rows = "E-1,1,Epic,In Progress,01/Mar/24 09:00 AM,,rollup,13,\n" +
+		"S-2,2,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,3,1\n" +
+		"S-3,3,Story,To Do,01/Mar/24 09:00 AM,,,5,1\n" +
+		"T-4,4,Sub-task,To Do,02/Mar/24 09:00 AM,,,1,3\n" +
+		"E-5,5,Epic,In Progress,01/Mar/24 09:00 AM,,other,8,\n" +
+		"S-6,6,Story,Done,01/Mar/24 09:00 AM,04/Mar/24 09:00 AM,,2,5\n" +
+		"S-7,7,Story,To Do,01/Mar/24 09:00 AM,,,4,5\n"
+	tests := []struct {
+		name        string
+		label       string
+		wantPresent []string
+		wantAbsent  []string
+		wantLeaves  map[string]float64
+		wantTotal   float64
+	}{
+		{
+			name:        "no rollup label",
+			wantPresent: []string{"E-1", "S-2", "S-3", "T-4", "E-5", "S-6", "S-7"},
+			wantLeaves:  map[string]float64{"S-2": 3, "T-4": 1, "S-6": 2, "S-7": 4},
+			wantTotal:   10,
+		},
+		{
+			name:        "labeled parent rolled up",
+			label:       "rollup",
+			wantPresent: []string{"E-1", "E-5", "S-6", "S-7"},
+			wantAbsent:  []string{"S-2", "S-3", "T-4"},
+			wantLeaves:  map[string]float64{"E-1": 13, "S-6": 2, "S-7": 4},
+			wantTotal:   19,
+		},
+		{
+			name:        "label on no parent",
+			label:       "missing",
+			wantPresent: []string{"E-1", "S-2", "S-3", "T-4", "E-5", "S-6", "S-7"},
+			wantLeaves:  map[string]float64{"S-2": 3, "T-4": 1, "S-6": 2, "S-7": 4},
+			wantTotal:   10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			opts.RollupLabel = tt.label
+			backlog := parseTestBacklog(t, rows, opts)
+			for _, id := range tt.wantPresent {
+				if _, ok := itemByID(backlog, id); !ok {
+					t.Errorf("%s missing from the backlog", id)
+				}
+			}
+			for _, id := range tt.wantAbsent {
+				if _, ok := itemByID(backlog, id); ok {
+					t.Errorf("%s kept though it is under a rolled up parent", id)
+				}
+			}
+			for id, points := range tt.wantLeaves {
+				item, _ := itemByID(backlog, id)
+				if item.hasChildren || item.points != points {
+					t.Errorf("%s has children %v and %g points, want a leaf of %g", id, item.hasChildren, item.points, points)
+				}
+			}
+			if total := ComputeTotals(backlog, opts).TotalPoints; total != tt.wantTotal {
+				t.Errorf("total points = %g, want %g", total, tt.wantTotal)
+			}
+		})
+	}
+}
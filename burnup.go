@@ -0,0 +1,350 @@
+// Package burnup builds burn-up chart data from a JIRA "Excel CSV (all fields)" export.  A backlog is parsed
+// with ParseBacklog, aggregated into running totals with ComputeTotals and written out as a set of CSV files
+// with WriteOutputs.
+package burnup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Date formats
+const jiraDate = "02/Jan/06 15:04 PM" // Format that JIRA uses
+const isoDate = "2006-01-02"          // ISO 8601
+const isoMonth = "2006-01"            // ISO 8601 calendar month
+
+// Totals aggregation periods
+const PeriodDaily = "daily"
+const PeriodMonthly = "monthly"
+const PeriodQuarterly = "quarterly" // Daily totals split into a file for each fiscal quarter
+
+// Ways of fixing items resolved before they were created
+const FixDatesNone = "none" // Leave the dates as they are
+const FixDatesSwap = "swap" // Swap the created and resolved dates
+const FixDatesDrop = "drop" // Drop the resolved date treating the item as still open
+
+// Levels of the hierarchy whose story points are counted
+const PointsLevelLeaf = "leaf"                    // Count only the points of leaf items
+const PointsLevelLowestPointed = "lowest-pointed" // Count the points of the deepest items that have any
+
+// Levels of the hierarchy treated as the leaves
+const LeafLevelItem = "item"   // The lowest items, whatever their type
+const LeafLevelStory = "story" // Items whose children are all sub-tasks, ignoring the sub-tasks
+
+// Dates the scope of an item can be counted at
+const ScopeAtOpened = "opened" // Count scope when items are opened
+const ScopeAtClosed = "closed" // Also write totals counting the scope of closed items when they are closed
+
+// Orders the items of the No Points audit can be sorted in
+const AuditSortID = "id"         // By id
+const AuditSortOpened = "opened" // By opened date, newest first
+const AuditSortType = "type"     // By type
+
+// Items listed in the backlog snapshot
+const SnapshotAll = "all"            // Every leaf item
+const SnapshotOpenOnly = "open-only" // Only the leaf items still open
+
+// Breakdowns of the opened and closed points in the totals
+const StackByNone = ""     // No breakdown
+const StackByType = "type" // A pair of columns per item type
+
+// Classes of error returned by the package so that callers can tell them apart with errors.Is
+var ErrParse = errors.New("unable to parse input")  // The input could not be read or parsed
+var ErrWrite = errors.New("unable to write output") // An output file could not be written to disk
+var ErrValidation = errors.New("failed validation") // An option or input value failed validation
+var ErrRowLimit = errors.New("too many rows")       // The input has more rows than the limit allows
+var ErrInterrupted = errors.New("interrupted")      // The run was interrupted before it completed
+
+// Options controlling how a backlog is parsed, aggregated and written
+type Options struct {
+	OutputDir         string             // Directory under which the output files are written
+	NameTemplate      string             // Template naming each output file from its kind, the run date and the project
+	Project           string             // Name of the project the input belongs to, which its items are tagged with
+	ByProject         bool               // Add the project to the snapshot and write a set of totals for each project
+	Strict            bool               // Reject an export with columns that are neither known fields nor named in the options
+	Period            string             // Totals aggregation period
+	FiscalStartMonth  time.Month         // Month the fiscal year starts in, used to split the totals by fiscal quarter
+	Precision         int                // Number of decimal places used for point values
+	MaxPoints         float64            // Story point value above which an item is considered suspect
+	SkipSuspectPoints bool               // Skip leaf items whose story points are negative or exceed MaxPoints
+	MinPoints         float64            // Story point value below which pointed leaf items are dropped, none when zero
+	InheritPoints     bool               // Distribute a parent's points across its unpointed leaf children
+	RollupLabel       string             // Label marking a parent whose own points are counted in place of its children's
+	LeafLevel         string             // Level of the hierarchy treated as the leaves
+	PointsLevel       string             // Level of the hierarchy whose story points are counted
+	FixDates          string             // How to fix items resolved before they were created
+	PointsFields      []string           // Names of the CSV columns whose story points are summed, the default column when empty
+	TypeField         string             // Name of the CSV column holding the issue type
+	ParentField       string             // Name of the CSV column linking an item to its parent by id or key
+	Columns           map[string]string  // Column names overriding the defaults of the fields without an option of their own
+	ClosedField       string             // Name of the CSV column holding the date an item was closed
+	Partial           bool               // Count the points done according to the remaining estimate of open items as closed
+	RemainingField    string             // Name of the CSV column holding the remaining estimate in points
+	SprintField       string             // Name of the CSV column holding the sprint used to report velocity
+	ResolutionField   string             // Name of the CSV column whose value marks an item as closed, falling back to its updated date
+	GroupBy           string             // Name of the CSV column to produce a separate totals file for each value of
+	Snapshot          string             // Items listed in the backlog snapshot
+	AuditSort         string             // Order the items of the No Points audit are sorted in
+	AuditParents      bool               // Write an audit of resolved parents for milestone tracking
+	Truncate          bool               // Remove the output files of earlier runs from the output directory before writing
+	Atomic            bool               // Write each output to a temporary file and rename it into place
+	UnitPoints        map[string]float64 // Points per unit of the estimate units, such as "d", story points may end in
+	DecimalComma      bool               // Story points use a comma rather than a period as the decimal separator
+	NoQuote           bool               // Quote only the output fields that need it rather than every text field
+	BareHeaders       bool               // Quote only the column names that need it, whatever the quoting of the data rows
+	Anonymize         bool               // Replace issue ids and keys in the snapshot and audits with hashed tokens
+	TotalsOut         io.Writer          // Writer the totals are written to in place of a file, such as stdout, when set
+	Format            string             // Name of the registered output format the totals are written in
+	Chart             string             // Format of the burn-up chart to render, none when empty
+	CloseLag          int                // Number of days closes are shifted later by in the totals
+	StackBy           string             // Breakdown of the opened and closed points in the totals, none when empty
+	ScopeAt           string             // Date the scope of an item is counted at in the variant totals
+	SkipEmptyDays     bool               // Leave rows without any points opened or closed out of the totals
+	Transpose         bool               // Write the totals with the dates across the top and a row for each column
+	Verbose           bool               // Log progress and other informational messages
+	Warnings          *WarningTally      // Tally the warnings are counted in for the end of run summary, not counted when nil
+	LogJSON           bool               // Log messages as single line JSON objects rather than free text
+	ExcludeKeys       map[string]bool    // Issue keys and ids of items dropped from the backlog
+	MaxRows           int                // Number of data rows above which reading is aborted, unlimited when zero
+	ProgressInterval  int                // Number of rows between progress messages when verbose
+	Delimiter         rune               // Field delimiter of the input
+	InstanceZone      *time.Location     // Time zone of the JIRA instance the export timestamps are in, UTC when nil
+	Zone              *time.Location     // Time zone dates are reported in, the instance time zone when nil
+	Start             time.Time          // Start of the reporting window, activity before it is left out of the totals
+	End               time.Time          // End of the reporting window, activity after it is left out of the totals
+	AssumeClosed      bool               // Treat items in a done status without a resolved date as closed on the run date
+	DropFuture        bool               // Drop items created or resolved after the run date rather than only warning about them
+	DoneStatuses      []string           // Statuses of finished work, which resolved items must be in to count as closed when given
+	ReopenStatuses    []string           // Statuses of reopened items, whose past close is reversed on the run date
+	AsOf              time.Time          // Date the backlog is looked at as of, in place of the run date, when set
+	Baseline          bool               // Carry points opened and closed before Start into the starting cumulative values
+	History           string             // Path of a CSV file the grand totals of each run are appended to, none when empty
+	Interrupt         <-chan struct{}    // Closed to stop the run once the file being written is complete
+	Inputs            []string           // Names of the inputs, recorded in the run manifest
+	Flags             map[string]string  // Command line flags in effect, recorded in the run manifest
+}
+
+// DefaultOptions returns the options the command line tool uses when no flags are given
+func DefaultOptions() Options {
+	return Options{
+		OutputDir:        "Burnup",
+		NameTemplate:     DefaultNameTemplate,
+		Period:           PeriodDaily,
+		FiscalStartMonth: time.January,
+		Precision:        2,
+		MaxPoints:        100,
+		FixDates:         FixDatesNone,
+		LeafLevel:        LeafLevelItem,
+		PointsLevel:      PointsLevelLeaf,
+		ScopeAt:          ScopeAtOpened,
+		Snapshot:         SnapshotAll,
+		Format:           FormatCSV,
+		AuditSort:        AuditSortID,
+		TypeField:        fieldIssueType,
+		ParentField:      fieldParentKey,
+		ClosedField:      fieldResolved,
+		RemainingField:   "Remaining Estimate",
+		SprintField:      "Sprint",
+		Delimiter:        ',',
+		ProgressInterval: 50000,
+		Warnings:         &WarningTally{},
+	}
+}
+
+// Validate checks that the options hold usable values
+func (opts Options) Validate() error {
+	if opts.Period != PeriodDaily && opts.Period != PeriodMonthly && opts.Period != PeriodQuarterly {
+		return fmt.Errorf("%w: unknown aggregation period \"%s\"", ErrValidation, opts.Period)
+	}
+	if opts.FiscalStartMonth < time.January || opts.FiscalStartMonth > time.December {
+		return fmt.Errorf("%w: the fiscal year must start in a month from 1 to 12, not %d", ErrValidation, opts.FiscalStartMonth)
+	}
+	if opts.FixDates != FixDatesNone && opts.FixDates != FixDatesSwap && opts.FixDates != FixDatesDrop {
+		return fmt.Errorf("%w: unknown date fix \"%s\"", ErrValidation, opts.FixDates)
+	}
+	if opts.LeafLevel != LeafLevelItem && opts.LeafLevel != LeafLevelStory {
+		return fmt.Errorf("%w: unknown leaf level \"%s\"", ErrValidation, opts.LeafLevel)
+	}
+	if opts.PointsLevel != PointsLevelLeaf && opts.PointsLevel != PointsLevelLowestPointed {
+		return fmt.Errorf("%w: unknown points level \"%s\"", ErrValidation, opts.PointsLevel)
+	}
+	if opts.TypeField == "" {
+		return fmt.Errorf("%w: the issue type column must be named", ErrValidation)
+	}
+	if opts.ParentField == "" {
+		return fmt.Errorf("%w: the parent column must be named", ErrValidation)
+	}
+	if opts.ClosedField == "" {
+		return fmt.Errorf("%w: the closed date column must be named", ErrValidation)
+	}
+	if opts.Delimiter == 0 || opts.Delimiter == '"' || opts.Delimiter == '\r' || opts.Delimiter == '\n' || opts.Delimiter == utf8.RuneError {
+		return fmt.Errorf("%w: delimiter %q cannot be used to separate fields", ErrValidation, opts.Delimiter)
+	}
+	if opts.MinPoints < 0 {
+		return fmt.Errorf("%w: the minimum points cannot be negative", ErrValidation)
+	}
+	if opts.CloseLag < 0 {
+		return fmt.Errorf("%w: the close lag cannot be negative", ErrValidation)
+	}
+	if opts.MaxRows < 0 {
+		return fmt.Errorf("%w: the row limit cannot be negative", ErrValidation)
+	}
+	if opts.Precision < 0 || opts.Precision > 6 {
+		return fmt.Errorf("%w: precision must be between 0 and 6, not %d", ErrValidation, opts.Precision)
+	}
+	if opts.StackBy != StackByNone && opts.StackBy != StackByType {
+		return fmt.Errorf("%w: unknown breakdown \"%s\"", ErrValidation, opts.StackBy)
+	}
+	if opts.ScopeAt != ScopeAtOpened && opts.ScopeAt != ScopeAtClosed {
+		return fmt.Errorf("%w: unknown scope date \"%s\"", ErrValidation, opts.ScopeAt)
+	}
+	if opts.Snapshot != SnapshotAll && opts.Snapshot != SnapshotOpenOnly {
+		return fmt.Errorf("%w: unknown snapshot contents \"%s\"", ErrValidation, opts.Snapshot)
+	}
+	if opts.AuditSort != AuditSortID && opts.AuditSort != AuditSortOpened && opts.AuditSort != AuditSortType {
+		return fmt.Errorf("%w: unknown audit sort order \"%s\"", ErrValidation, opts.AuditSort)
+	}
+	if err := checkNameTemplate(opts); err != nil {
+		return err
+	}
+	if _, ok := outputWriters[opts.Format]; !ok {
+		return fmt.Errorf("%w: unknown output format \"%s\"", ErrValidation, opts.Format)
+	}
+	if opts.Chart != "" && opts.Chart != ChartSVG {
+		return fmt.Errorf("%w: unknown chart format \"%s\"", ErrValidation, opts.Chart)
+	}
+	if !opts.Start.IsZero() && !opts.End.IsZero() && opts.End.Before(opts.Start) {
+		return fmt.Errorf("%w: the reporting window ends before it starts", ErrValidation)
+	}
+	if opts.Baseline && opts.Start.IsZero() {
+		return fmt.Errorf("%w: a baseline requires the start of the reporting window", ErrValidation)
+	}
+	return nil
+}
+
+// Date of the run, which is the as-of date when one is given
+func (opts Options) runDate() time.Time {
+	if !opts.AsOf.IsZero() {
+		return opts.AsOf
+	}
+	return time.Now()
+}
+
+// Start of the day after the run date in the given time zone.  The as-of date is a calendar date so it is taken
+// as that same day in the zone rather than converted into it
+func (opts Options) dayAfterRunDate(zone *time.Location) time.Time {
+	runDate := opts.AsOf
+	if runDate.IsZero() {
+		runDate = time.Now().In(zone)
+	}
+	year, month, day := runDate.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, zone)
+}
+
+// MapField maps a field, such as "points" or "closed", to the name of the export column holding it in place of
+// its default column
+func (opts *Options) MapField(field string, column string) error {
+	switch field {
+	case "type":
+		opts.TypeField = column
+	case "parent":
+		opts.ParentField = column
+	case "closed":
+		opts.ClosedField = column
+	case "sprint":
+		opts.SprintField = column
+	case "resolution":
+		opts.ResolutionField = column
+	default:
+		if _, ok := defaultColumns[field]; !ok {
+			return fmt.Errorf("%w: unknown field \"%s\"", ErrValidation, field)
+		}
+		if opts.Columns == nil {
+			opts.Columns = make(map[string]string)
+		}
+		opts.Columns[field] = column
+	}
+	return nil
+}
+
+// Name of the export column holding a field without an option of its own
+func (opts Options) column(field string) string {
+	if column, ok := opts.Columns[field]; ok {
+		return column
+	}
+	return defaultColumns[field]
+}
+
+// Whether the run has been interrupted
+func (opts Options) interrupted() bool {
+	select {
+	case <-opts.Interrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// AddReopenStatuses adds the statuses in a comma separated list to those marking an item as reopened
+func (opts *Options) AddReopenStatuses(statuses string) {
+	opts.ReopenStatuses = append(opts.ReopenStatuses, splitStatuses(statuses)...)
+}
+
+// AddDoneStatuses adds the statuses in a comma separated list to those of finished work
+func (opts *Options) AddDoneStatuses(statuses string) {
+	opts.DoneStatuses = append(opts.DoneStatuses, splitStatuses(statuses)...)
+}
+
+// Whether a status is one of those marking an item as reopened
+func (opts Options) isReopenStatus(status string) bool {
+	return containsStatus(opts.ReopenStatuses, status)
+}
+
+// Whether a status is one of finished work, either those given or JIRA's usual terminal statuses
+func (opts Options) isDoneStatus(status string) bool {
+	if len(opts.DoneStatuses) == 0 {
+		return isDoneStatus(status)
+	}
+	return containsStatus(opts.DoneStatuses, status)
+}
+
+// Split a comma separated list of statuses, leaving out blanks
+func splitStatuses(statuses string) []string {
+	var split []string
+	for _, status := range strings.Split(statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			split = append(split, status)
+		}
+	}
+	return split
+}
+
+// Whether a status is in a list of statuses, ignoring case
+func containsStatus(statuses []string, status string) bool {
+	for _, listed := range statuses {
+		if strings.EqualFold(strings.TrimSpace(status), listed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddEstimateUnit allows story points to be given in a unit, such as "d" for days, each of which is worth the
+// given number of points
+func (opts *Options) AddEstimateUnit(unit string, points float64) error {
+	if unit == "" || strings.ContainsAny(unit, "0123456789.,") {
+		return fmt.Errorf("%w: estimate unit \"%s\" must be a non-numeric suffix", ErrValidation, unit)
+	}
+	if points <= 0 {
+		return fmt.Errorf("%w: estimate unit \"%s\" must be worth more than zero points", ErrValidation, unit)
+	}
+	if opts.UnitPoints == nil {
+		opts.UnitPoints = make(map[string]float64)
+	}
+	opts.UnitPoints[unit] = points
+	return nil
+}
@@ -0,0 +1,106 @@
+package burnup
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Burn-up chart output formats
+const ChartSVG = "svg"
+
+// Dimensions of the rendered chart in pixels
+const chartWidth = 800
+const chartHeight = 450
+const chartMarginLeft = 60
+const chartMarginRight = 20
+const chartMarginTop = 40
+const chartMarginBottom = 60
+const chartYTicks = 5
+const chartXTicks = 6
+
+// Line colors of the chart series
+const chartScopeColor = "#1f77b4"
+const chartDoneColor = "#2ca02c"
+
+// Render the burn-up chart of cumulative opened (scope) and cumulative closed (done) points over the totals
+// dates as a standalone SVG document
+func renderChartSVG(totals *Totals, opts Options) string {
+	rows := totals.rows
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+
+	// Scale the y axis to a round number just above the largest cumulative value
+	maxPoints := 0.0
+	for _, row := range rows {
+		maxPoints = math.Max(maxPoints, math.Max(row.cumulativeOpened, row.cumulativeClosed))
+	}
+	yMax := niceCeiling(maxPoints)
+	x := func(i int) float64 {
+		if len(rows) < 2 {
+			return chartMarginLeft
+		}
+		return chartMarginLeft + plotWidth*float64(i)/float64(len(rows)-1)
+	}
+	y := func(points float64) float64 {
+		return chartMarginTop + plotHeight - plotHeight*points/yMax
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"12\">\n", chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&svg, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+	// Axes with gridlines and tick labels
+	for tick := 0; tick <= chartYTicks; tick++ {
+		points := yMax * float64(tick) / chartYTicks
+		fmt.Fprintf(&svg, "<line x1=\"%d\" y1=\"%.1f\" x2=\"%d\" y2=\"%.1f\" stroke=\"#ddd\"/>\n", chartMarginLeft, y(points), chartWidth-chartMarginRight, y(points))
+		fmt.Fprintf(&svg, "<text x=\"%d\" y=\"%.1f\" text-anchor=\"end\" dominant-baseline=\"middle\">%s</text>\n", chartMarginLeft-6, y(points), formatTick(points))
+	}
+	if len(rows) > 0 {
+		step := (len(rows) + chartXTicks - 1) / chartXTicks
+		for i := 0; i < len(rows); i += step {
+			fmt.Fprintf(&svg, "<text x=\"%.1f\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", x(i), chartHeight-chartMarginBottom+18, rows[i].date.Format(isoDate))
+		}
+	}
+	fmt.Fprintf(&svg, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n", chartMarginLeft, chartMarginTop, chartMarginLeft, chartHeight-chartMarginBottom)
+	fmt.Fprintf(&svg, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n", chartMarginLeft, chartHeight-chartMarginBottom, chartWidth-chartMarginRight, chartHeight-chartMarginBottom)
+	fmt.Fprintf(&svg, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">Date</text>\n", chartMarginLeft+int(plotWidth)/2, chartHeight-15)
+	fmt.Fprintf(&svg, "<text x=\"15\" y=\"%d\" text-anchor=\"middle\" transform=\"rotate(-90 15 %d)\">Points</text>\n", chartMarginTop+int(plotHeight)/2, chartMarginTop+int(plotHeight)/2)
+
+	// Scope and done lines
+	var scope, done strings.Builder
+	for i, row := range rows {
+		fmt.Fprintf(&scope, "%.1f,%.1f ", x(i), y(row.cumulativeOpened))
+		fmt.Fprintf(&done, "%.1f,%.1f ", x(i), y(row.cumulativeClosed))
+	}
+	fmt.Fprintf(&svg, "<polyline fill=\"none\" stroke=\"%s\" stroke-width=\"2\" points=\"%s\"/>\n", chartScopeColor, strings.TrimSpace(scope.String()))
+	fmt.Fprintf(&svg, "<polyline fill=\"none\" stroke=\"%s\" stroke-width=\"2\" points=\"%s\"/>\n", chartDoneColor, strings.TrimSpace(done.String()))
+
+	// Legend
+	fmt.Fprintf(&svg, "<line x1=\"%d\" y1=\"20\" x2=\"%d\" y2=\"20\" stroke=\"%s\" stroke-width=\"2\"/>\n", chartMarginLeft, chartMarginLeft+20, chartScopeColor)
+	fmt.Fprintf(&svg, "<text x=\"%d\" y=\"20\" dominant-baseline=\"middle\">Scope</text>\n", chartMarginLeft+26)
+	fmt.Fprintf(&svg, "<line x1=\"%d\" y1=\"20\" x2=\"%d\" y2=\"20\" stroke=\"%s\" stroke-width=\"2\"/>\n", chartMarginLeft+90, chartMarginLeft+110, chartDoneColor)
+	fmt.Fprintf(&svg, "<text x=\"%d\" y=\"20\" dominant-baseline=\"middle\">Done</text>\n", chartMarginLeft+116)
+
+	fmt.Fprintf(&svg, "</svg>\n")
+	return svg.String()
+}
+
+// Round a value up to a one, two or five multiple of a power of ten so that axis ticks fall on round numbers
+func niceCeiling(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, multiple := range []float64{1, 2, 5, 10} {
+		if value <= multiple*magnitude {
+			return multiple * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// Format an axis tick value without trailing zeros
+func formatTick(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ptdecker/burnup"
+)
+
+// Process exit codes
+const exitParseError = 2      // The input could not be read or parsed
+const exitWriteError = 3      // An output file could not be written to disk
+const exitValidationError = 4 // A command line option or input value failed validation
+const exitRowLimit = 5        // The input has more rows than -max-rows allows
+const exitInterrupted = 130   // The run was interrupted by a signal
+
+// How often the input is checked for changes when watching it
+const watchInterval = 2 * time.Second
+
+// Flag left out of the usage as it is intended for wrapper scripts rather than people
+const hiddenFlagsJSON = "flags-json"
+
+// Description of a flag for wrapper scripts introspecting the tool
+type flagDescription struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// Repeatable flag mapping a field to the export column holding it
+type fieldMapping struct{}
+
+func (fieldMapping) String() string {
+	return ""
+}
+
+func (fieldMapping) Set(value string) error {
+	field, column, ok := strings.Cut(value, "=")
+	if !ok || column == "" {
+		return fmt.Errorf("mapping must be given as field=column, not \"%s\"", value)
+	}
+	return opts.MapField(strings.TrimSpace(field), column)
+}
+
+// Repeatable flag giving the points each unit of an estimate unit is worth
+type estimateUnit struct{}
+
+func (estimateUnit) String() string {
+	return ""
+}
+
+func (estimateUnit) Set(value string) error {
+	unit, rate, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("estimate unit must be given as unit=points, not \"%s\"", value)
+	}
+	points, err := strconv.ParseFloat(rate, 64)
+	if err != nil {
+		return fmt.Errorf("estimate unit must be given as unit=points, not \"%s\"", value)
+	}
+	return opts.AddEstimateUnit(strings.TrimSpace(unit), points)
+}
+
+// Repeatable flag naming a story point column
+type pointsField struct{}
+
+func (pointsField) String() string {
+	return ""
+}
+
+func (pointsField) Set(value string) error {
+	opts.PointsFields = append(opts.PointsFields, value)
+	return nil
+}
+
+// Repeatable flag listing statuses of finished work, comma separated
+type doneStatuses struct{}
+
+func (doneStatuses) String() string {
+	return ""
+}
+
+func (doneStatuses) Set(value string) error {
+	opts.AddDoneStatuses(value)
+	return nil
+}
+
+// Repeatable flag listing statuses of reopened items, comma separated
+type reopenStatuses struct{}
+
+func (reopenStatuses) String() string {
+	return ""
+}
+
+func (reopenStatuses) Set(value string) error {
+	opts.AddReopenStatuses(value)
+	return nil
+}
+
+// Flag giving the month the fiscal year starts in as a number
+type fiscalMonth struct{}
+
+func (fiscalMonth) String() string {
+	return strconv.Itoa(int(opts.FiscalStartMonth))
+}
+
+func (fiscalMonth) Get() interface{} {
+	return int(opts.FiscalStartMonth)
+}
+
+func (fiscalMonth) Set(value string) error {
+	month, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("month must be a number from 1 to 12, not \"%s\"", value)
+	}
+	opts.FiscalStartMonth = time.Month(month)
+	return nil
+}
+
+// Options built up from the command line flags
+var opts = burnup.DefaultOptions()
+
+// Command line flags which are not options of the burnup package
+var flagsJSON = flag.Bool(hiddenFlagsJSON, false, "print the defined flags as JSON and exit")
+var skipUnchanged = flag.Bool("skip-unchanged", false, "exit without rewriting the outputs when the input, flags and run date are unchanged since the last run")
+var dumpItems = flag.Bool("dump-items", false, "print the parsed backlog items as JSON to stdout and exit without writing any files")
+var verify = flag.Bool("verify", false, "check that the points opened and closed in the totals add up to those of the leaf items before writing any files")
+var stdout = flag.Bool("stdout", false, "write the totals to stdout in place of the Totals file")
+var compare = flag.String("compare", "", "compare this Totals file with the one given after the options, writing the variance in cumulative closed points, and exit")
+var summary = flag.Bool("summary", false, "print a summary table to stdout after writing the output files")
+var start = flag.String("start", "", "start of the reporting window as YYYY-MM-DD, activity before it is left out of the totals which begin on it")
+var end = flag.String("end", "", "end of the reporting window as YYYY-MM-DD, activity after it is left out of the totals which end on it")
+var asOf = flag.String("as-of", "", "date as YYYY-MM-DD to report the backlog as of, leaving out items created after it and reopening items resolved after it")
+var instanceZone = flag.String("instance-tz", "", "IANA time zone of the JIRA instance the export timestamps are in (default UTC)")
+var zone = flag.String("tz", "", "IANA time zone, e.g. America/Chicago or UTC, dates are converted to before being reported")
+var input = flag.String("input", "", "path or http(s) URL of the export to read in place of stdin, optionally followed by =project to tag its items with, e.g. proj.csv=ProjectA (a path that names an existing file is read as given)")
+var header = flag.String("header", "", "HTTP header, e.g. \"Authorization: Bearer ...\", sent when -input is a URL")
+var excludeKeysFile = flag.String("exclude-keys-file", "", "path of a file listing issue keys or ids, one per line, of items to drop from the backlog")
+var delimiter = flag.String("delimiter", ",", "single character field delimiter of the input (use '\\t' for tab-separated input)")
+var fromSnapshot = flag.Bool("from-snapshot", false, "read a Backlog Snapshot written by an earlier run in place of a JIRA export, recomputing the totals and audits from it")
+var watch = flag.Bool("watch", false, "keep running, regenerating the outputs whenever the -input file changes")
+
+func init() {
+	flag.StringVar(&opts.Period, "period", opts.Period, "totals aggregation period (\""+burnup.PeriodDaily+"\", \""+burnup.PeriodMonthly+"\" or \""+burnup.PeriodQuarterly+"\" for daily totals split into a file per fiscal quarter)")
+	flag.Var(fiscalMonth{}, "fiscal-start-month", "month from 1 to 12 the fiscal year starts in, used by -period "+burnup.PeriodQuarterly)
+	flag.IntVar(&opts.Precision, "precision", opts.Precision, "number of decimal places used for point values (0-6)")
+	flag.Float64Var(&opts.MaxPoints, "max-points", opts.MaxPoints, "story point value above which an item is considered suspect")
+	flag.Float64Var(&opts.MinPoints, "min-points", opts.MinPoints, "story point value below which pointed leaf items are dropped from the totals and snapshot into an audit of their own (0 keeps everything)")
+	flag.StringVar(&opts.RollupLabel, "rollup-label", opts.RollupLabel, "label marking a parent estimated as a whole, whose own points are counted and whose children are left out")
+	flag.BoolVar(&opts.InheritPoints, "inherit-points", opts.InheritPoints, "distribute a parent's points evenly across its unpointed leaf children")
+	flag.StringVar(&opts.LeafLevel, "leaf-level", opts.LeafLevel, "level of the hierarchy treated as the leaves (\""+burnup.LeafLevelItem+"\" or \""+burnup.LeafLevelStory+"\" to count stories in place of their sub-tasks)")
+	flag.StringVar(&opts.PointsLevel, "points-level", opts.PointsLevel, "level of the hierarchy whose story points are counted (\""+burnup.PointsLevelLeaf+"\" or \""+burnup.PointsLevelLowestPointed+"\" to also count pointed parents without pointed descendants)")
+	flag.BoolVar(&opts.SkipSuspectPoints, "skip-suspect-points", opts.SkipSuspectPoints, "skip leaf items whose story points are negative or exceed -max-points")
+	flag.StringVar(&opts.FixDates, "fix-dates", opts.FixDates, "how to fix items resolved before they were created (\""+burnup.FixDatesNone+"\", \""+burnup.FixDatesSwap+"\" or \""+burnup.FixDatesDrop+"\")")
+	flag.Var(fieldMapping{}, "map", "map a field to the CSV column holding it as field=column, repeatable (fields: id, key, type, status, created, closed, labels, points, parent, updated, assignee, sprint, resolution)")
+	flag.Var(pointsField{}, "points-field", "name of a CSV column holding story points, repeatable to sum several such as during a migration")
+	flag.StringVar(&opts.TypeField, "type-field", opts.TypeField, "name of the CSV column holding the issue type, e.g. Type")
+	flag.StringVar(&opts.ParentField, "parent-field", opts.ParentField, "name of the CSV column linking an item to its parent by issue id or key, e.g. Epic Link")
+	flag.StringVar(&opts.ClosedField, "closed-field", opts.ClosedField, "name of the CSV column holding the date an item was closed, e.g. a custom completed date field")
+	flag.BoolVar(&opts.Partial, "partial", opts.Partial, "count the points done according to the remaining estimate of open items as closed on their updated date")
+	flag.StringVar(&opts.RemainingField, "remaining-field", opts.RemainingField, "name of the CSV column holding the remaining estimate in points used by -partial")
+	flag.StringVar(&opts.SprintField, "sprint-field", opts.SprintField, "name of the CSV column holding the sprint used to report velocity")
+	flag.StringVar(&opts.ResolutionField, "resolution-field", opts.ResolutionField, "name of the CSV column whose non-empty value marks an item as closed, using its updated date when it has no resolved date")
+	flag.StringVar(&opts.GroupBy, "group-by", opts.GroupBy, "name of a CSV column, such as Component/s, to write a separate totals file for each value of")
+	flag.StringVar(&opts.Snapshot, "snapshot", opts.Snapshot, "leaf items listed in the backlog snapshot (\""+burnup.SnapshotAll+"\" or \""+burnup.SnapshotOpenOnly+"\")")
+	flag.StringVar(&opts.AuditSort, "audit-sort", opts.AuditSort, "order of the No Points audit (\""+burnup.AuditSortID+"\", \""+burnup.AuditSortOpened+"\" for newest first or \""+burnup.AuditSortType+"\")")
+	flag.BoolVar(&opts.AuditParents, "include-parents-in-audit", opts.AuditParents, "write an audit of resolved parents with their resolution dates")
+	flag.StringVar(&opts.History, "history", opts.History, "path of a CSV file to append the run date and grand totals to, created with a header when absent")
+	flag.BoolVar(&opts.Truncate, "truncate", opts.Truncate, "remove the dated output files of earlier runs from the output directory and its Snapshots, Audits and Totals directories before writing, leaving any other files alone")
+	flag.StringVar(&opts.NameTemplate, "name-template", opts.NameTemplate, "Go template naming the output files from {{.Kind}}, {{.Date}} and {{.Project}}, e.g. \"TeamA_{{.Kind}}_{{.Date}}\"")
+	flag.StringVar(&opts.Project, "project", opts.Project, "name of the project the input belongs to, which its items are tagged with and is available to -name-template")
+	flag.BoolVar(&opts.ByProject, "by-project", opts.ByProject, "add a project column to the backlog snapshot and write a set of totals for each project")
+	flag.BoolVar(&opts.Atomic, "atomic", opts.Atomic, "write each output to a temporary file and rename it into place so readers never see a partial file")
+	flag.Var(estimateUnit{}, "estimate-unit", "allow story points to end in a unit worth the given points, as unit=points, e.g. d=1 or h=0.125, repeatable")
+	flag.BoolVar(&opts.DecimalComma, "decimal-comma", opts.DecimalComma, "parse story points using a comma as the decimal separator, e.g. 5,0")
+	flag.BoolVar(&opts.Strict, "strict", opts.Strict, "fail when the export has columns that are neither known JIRA fields nor named by the options, catching schema drift")
+	flag.BoolVar(&opts.Verbose, "verbose", opts.Verbose, "log progress and other informational messages to stderr")
+	flag.BoolVar(&opts.LogJSON, "log-json", opts.LogJSON, "log warnings and errors as single line JSON objects for log aggregation")
+	flag.IntVar(&opts.MaxRows, "max-rows", opts.MaxRows, "number of input rows above which the run is aborted (0 for unlimited)")
+	flag.IntVar(&opts.ProgressInterval, "progress-every", opts.ProgressInterval, "number of rows between progress messages when -verbose")
+	flag.StringVar(&opts.Format, "format", opts.Format, "format the totals are written in (\""+strings.Join(burnup.Formats(), "\", \"")+"\")")
+	flag.BoolVar(&opts.NoQuote, "no-quote", opts.NoQuote, "quote only the output fields that need it, such as those holding commas or quotes")
+	flag.BoolVar(&opts.BareHeaders, "bare-headers", opts.BareHeaders, "write the column names of the header rows without quotes unless they need them")
+	flag.BoolVar(&opts.Anonymize, "anonymize", opts.Anonymize, "replace issue ids in the snapshot and audits with stable hashed tokens")
+	flag.StringVar(&opts.Chart, "chart", opts.Chart, "render the burn-up chart in the given format (\""+burnup.ChartSVG+"\")")
+	flag.IntVar(&opts.CloseLag, "close-lag", opts.CloseLag, "number of days to shift closes later by in the totals so same-day closes show a visible gap")
+	flag.StringVar(&opts.StackBy, "stack-by", opts.StackBy, "break the opened and closed points of the totals down into a pair of columns per value (\""+burnup.StackByType+"\")")
+	flag.StringVar(&opts.ScopeAt, "scope-at", opts.ScopeAt, "date scope is counted at (\""+burnup.ScopeAtOpened+"\" or \""+burnup.ScopeAtClosed+"\" to also write totals counting closed items' scope on their close date)")
+	flag.BoolVar(&opts.SkipEmptyDays, "skip-empty-days", opts.SkipEmptyDays, "leave days without any points opened or closed out of the totals")
+	flag.BoolVar(&opts.Transpose, "transpose", opts.Transpose, "write the totals with the dates across the top and a row for each of the other columns")
+	flag.BoolVar(&opts.AssumeClosed, "assume-closed-today", opts.AssumeClosed, "treat items in a done status without a resolved date as closed on the run date (or -as-of)")
+	flag.BoolVar(&opts.DropFuture, "drop-future", opts.DropFuture, "drop items created or resolved after the run date (or -as-of) rather than only warning about them")
+	flag.Var(doneStatuses{}, "done-status", "comma separated statuses of finished work, e.g. Done,Won't Do, which resolved items must be in to count as closed, repeatable (default Done, Closed and Resolved for -assume-closed-today)")
+	flag.Var(reopenStatuses{}, "reopen-status", "comma separated statuses, e.g. Reopened, of resolved items that have been reopened, whose points are taken back out of those closed on the run date (or -as-of), repeatable")
+	flag.BoolVar(&opts.Baseline, "baseline", opts.Baseline, "carry points opened and closed before -start into the starting cumulative totals, adding a baseline column giving them")
+	flag.BoolVar(&opts.Baseline, "closed-before-as-baseline", opts.Baseline, "same as -baseline")
+}
+
+// Set the input delimiter from its flag value, which must be a single character or the escaped tab "\t"
+func parseDelimiter(value string) error {
+	if value == `\t` {
+		value = "\t"
+	}
+	if utf8.RuneCountInString(value) != 1 {
+		return fmt.Errorf("%w: delimiter must be a single character, not \"%s\"", burnup.ErrValidation, value)
+	}
+	opts.Delimiter, _ = utf8.DecodeRuneInString(value)
+	return nil
+}
+
+// Parse the ISO 8601 date given as the value of a flag
+func parseDate(name string, value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: -%s must be a date formatted as YYYY-MM-DD, not \"%s\"", burnup.ErrValidation, name, value)
+	}
+	return date, nil
+}
+
+// Load the IANA time zone given as the value of a flag, leaving it unset when the flag is empty
+func loadZone(name string, value string) (*time.Location, error) {
+	if value == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: -%s must be an IANA time zone name, not \"%s\"", burnup.ErrValidation, name, value)
+	}
+	return location, nil
+}
+
+// Open the input, which is stdin when no name is given, an export fetched over HTTP when the name is a URL and
+// a file otherwise.  The name of the input returned for the manifest leaves out any query string of a URL as
+// it may hold credentials
+func openInput(name string, header string) (io.ReadCloser, string, error) {
+	if name == "" {
+		return os.Stdin, "stdin", nil
+	}
+	if !strings.HasPrefix(name, "http://") && !strings.HasPrefix(name, "https://") {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s", burnup.ErrParse, err)
+		}
+		return file, name, nil
+	}
+	request, err := http.NewRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: invalid input URL: %s", burnup.ErrValidation, err)
+	}
+	if header != "" {
+		key, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("%w: header must be given as \"Name: value\"", burnup.ErrValidation)
+		}
+		request.Header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", burnup.ErrParse, err)
+	}
+	displayName := request.URL.Scheme + "://" + request.URL.Host + request.URL.Path
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, "", fmt.Errorf("%w: fetching %s returned %s", burnup.ErrParse, displayName, response.Status)
+	}
+	return response.Body, displayName, nil
+}
+
+// Split the project a path given as the input is tagged with from it.  The project follows the last "=", which
+// is only taken as the separator when the whole value is neither an existing file nor an http(s) URL, so that a
+// path such as exports/sprint=12.csv is read as it is
+func splitInputProject(value string) (string, string) {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return value, ""
+	}
+	if _, err := os.Stat(value); err == nil {
+		return value, ""
+	}
+	i := strings.LastIndex(value, "=")
+	if i < 0 {
+		return value, ""
+	}
+	return value[:i], value[i+1:]
+}
+
+// Load the set of issue keys listed one per line in a file, ignoring blank lines
+func loadKeys(name string) (map[string]bool, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", burnup.ErrValidation, err)
+	}
+	defer file.Close()
+	keys := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key != "" {
+			keys[key] = true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", burnup.ErrValidation, err)
+	}
+	return keys, nil
+}
+
+// Stop the run once the file being written is complete on the first SIGINT or SIGTERM, aborting immediately on
+// a second
+func handleSignals() {
+	interrupt := make(chan struct{})
+	opts.Interrupt = interrupt
+	logOpts := opts
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		burnup.Logf(logOpts, burnup.LevelWarning, "", 0, "Interrupted, stopping once the file being written is complete (interrupt again to abort)")
+		close(interrupt)
+		<-signals
+		burnup.Logf(logOpts, burnup.LevelFatal, "", 0, "Aborted")
+		os.Exit(exitInterrupted)
+	}()
+}
+
+// Write the variance between two Totals files
+func compareTotals(firstName string, secondName string) error {
+	if secondName == "" {
+		return fmt.Errorf("%w: -compare needs a second Totals file after the options", burnup.ErrValidation)
+	}
+	first, err := os.Open(firstName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", burnup.ErrParse, err)
+	}
+	defer first.Close()
+	second, err := os.Open(secondName)
+	if err != nil {
+		return fmt.Errorf("%w: %s", burnup.ErrParse, err)
+	}
+	defer second.Close()
+	return burnup.WriteVariance(first, second, opts)
+}
+
+// Log a fatal error and exit with the exit code matching its cause
+func fatal(err error) {
+	code := 1
+	switch {
+	case errors.Is(err, burnup.ErrParse):
+		code = exitParseError
+	case errors.Is(err, burnup.ErrWrite):
+		code = exitWriteError
+	case errors.Is(err, burnup.ErrValidation):
+		code = exitValidationError
+	case errors.Is(err, burnup.ErrRowLimit):
+		code = exitRowLimit
+	case errors.Is(err, burnup.ErrInterrupted):
+		code = exitInterrupted
+	}
+	burnup.Logf(opts, burnup.LevelFatal, "", 0, "%s", err)
+	os.Exit(code)
+}
+
+// Print command line usage including the exit codes the tool may return
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] < export.csv\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] -input export.csv|URL\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] -compare first.csv second.csv\n\nOptions:\n", os.Args[0])
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != hiddenFlagsJSON {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	visible.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), "\nExit codes:\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  0\tsuccess\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  %d\tthe input could not be read or parsed\n", exitParseError)
+	fmt.Fprintf(flag.CommandLine.Output(), "  %d\tan output file could not be written to disk\n", exitWriteError)
+	fmt.Fprintf(flag.CommandLine.Output(), "  %d\ta command line option or input value failed validation\n", exitValidationError)
+	fmt.Fprintf(flag.CommandLine.Output(), "  %d\tthe input has more rows than -max-rows allows\n", exitRowLimit)
+	fmt.Fprintf(flag.CommandLine.Output(), "  %d\tthe run was interrupted by a signal\n", exitInterrupted)
+}
+
+// Print every defined flag with its type and default as JSON so that wrapper scripts can introspect the tool
+func printFlagsJSON() error {
+	var flags []flagDescription
+	flag.VisitAll(func(f *flag.Flag) {
+		flagType := "string"
+		if getter, ok := f.Value.(flag.Getter); ok {
+			flagType = fmt.Sprintf("%T", getter.Get())
+		}
+		flags = append(flags, flagDescription{
+			Name:    f.Name,
+			Type:    flagType,
+			Default: f.DefValue,
+			Usage:   f.Usage,
+		})
+	})
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(flags)
+}
+
+func main() {
+
+	flag.Usage = usage
+	flag.Parse()
+	if *flagsJSON {
+		err := printFlagsJSON()
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	err := parseDelimiter(*delimiter)
+	if err != nil {
+		fatal(err)
+	}
+	if *start != "" {
+		opts.Start, err = parseDate("start", *start)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if *end != "" {
+		opts.End, err = parseDate("end", *end)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if *asOf != "" {
+		opts.AsOf, err = parseDate("as-of", *asOf)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	opts.InstanceZone, err = loadZone("instance-tz", *instanceZone)
+	if err != nil {
+		fatal(err)
+	}
+	opts.Zone, err = loadZone("tz", *zone)
+	if err != nil {
+		fatal(err)
+	}
+	if *verify && (*start != "" || *end != "") {
+		fatal(fmt.Errorf("%w: -verify cannot be combined with -start or -end which leave activity out of the totals", burnup.ErrValidation))
+	}
+	if *stdout {
+		if *summary || *dumpItems {
+			fatal(fmt.Errorf("%w: -stdout cannot be combined with -summary or -dump-items which also write to stdout", burnup.ErrValidation))
+		}
+		opts.TotalsOut = os.Stdout
+	}
+	var project string
+	*input, project = splitInputProject(*input)
+	if project != "" {
+		opts.Project = project
+	}
+	if *watch {
+		if *input == "" || strings.HasPrefix(*input, "http://") || strings.HasPrefix(*input, "https://") {
+			fatal(fmt.Errorf("%w: -watch needs -input to name a file", burnup.ErrValidation))
+		}
+		if *dumpItems || *compare != "" {
+			fatal(fmt.Errorf("%w: -watch cannot be combined with -dump-items or -compare which exit after a single run", burnup.ErrValidation))
+		}
+	}
+	if *excludeKeysFile != "" {
+		opts.ExcludeKeys, err = loadKeys(*excludeKeysFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	handleSignals()
+	err = opts.Validate()
+	if err != nil {
+		fatal(err)
+	}
+
+	if !*dumpItems {
+		err = burnup.CheckOutputDir(opts)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if *compare != "" {
+		err = compareTotals(*compare, flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if *watch {
+		err = watchInput()
+	} else {
+		err = run()
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// Read the input, compute the totals and write the outputs
+func run() error {
+	source, inputName, err := openInput(*input, *header)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// Record the run for the manifest, leaving out the header as it may hold credentials
+	opts.Inputs = []string{inputName}
+	opts.Flags = make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		opts.Flags[f.Name] = f.Value.String()
+	})
+	opts.Flags["input"] = inputName
+	delete(opts.Flags, "header")
+
+	// Import backlog from JIRA, skipping the run when the input has not changed since the last one
+	var in io.Reader = bufio.NewReader(source)
+	var checksum string
+	if *skipUnchanged {
+		input, err := io.ReadAll(in)
+		if err != nil {
+			return fmt.Errorf("%w: %s", burnup.ErrParse, err)
+		}
+		checksum = burnup.InputChecksum(input, opts)
+		if burnup.InputUnchanged(checksum, opts) {
+			burnup.Logf(opts, burnup.LevelInfo, "", 0, "Input and options are unchanged since the last run so the outputs have not been rewritten")
+			return nil
+		}
+		in = bytes.NewReader(input)
+	}
+	var backlog *burnup.Backlog
+	if *fromSnapshot {
+		backlog, err = burnup.ParseSnapshot(in, opts)
+	} else {
+		backlog, err = burnup.ParseBacklog(in, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *dumpItems {
+		return backlog.WriteItemsJSON(os.Stdout)
+	}
+
+	totals := burnup.ComputeTotals(backlog, opts)
+	if *verify {
+		err = totals.Verify(opts)
+		if err != nil {
+			return err
+		}
+	}
+	err = burnup.WriteOutputs(backlog, totals, opts)
+	if err != nil {
+		return err
+	}
+	if *skipUnchanged {
+		err = burnup.RecordInput(checksum, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.Warnings.LogSummary(opts)
+
+	if *summary {
+		return burnup.WriteSummary(os.Stdout, totals, opts)
+	}
+	return nil
+}
+
+// Run once and then again whenever the modification time or size of the input file changes, until interrupted.
+// A change is only acted on once the file has stopped changing between two checks so that a file still being
+// written is not read part way through.  A failed run is logged and the input watched for the next change
+func watchInput() error {
+	err := run()
+	if err != nil {
+		return err
+	}
+	last, err := os.Stat(*input)
+	if err != nil {
+		return fmt.Errorf("%w: %s", burnup.ErrParse, err)
+	}
+	var pending os.FileInfo
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-opts.Interrupt:
+			return nil
+		case <-ticker.C:
+		}
+		current, err := os.Stat(*input)
+		if err != nil {
+			continue
+		}
+		if sameFileVersion(current, last) {
+			pending = nil
+			continue
+		}
+		if pending == nil || !sameFileVersion(current, pending) {
+			pending = current
+			continue
+		}
+		last, pending = current, nil
+		burnup.Logf(opts, burnup.LevelInfo, "", 0, "%s changed, regenerating the outputs", *input)
+		opts.Warnings = &burnup.WarningTally{}
+		err = run()
+		if err != nil {
+			if errors.Is(err, burnup.ErrInterrupted) {
+				return err
+			}
+			burnup.Logf(opts, burnup.LevelError, "", 0, "Regenerating the outputs failed: %s", err)
+		}
+	}
+}
+
+// Whether two looks at a file saw the same version of the file
+func sameFileVersion(a os.FileInfo, b os.FileInfo) bool {
+	return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSplitInputProject(t *testing.T) {
+	dir := t.TempDir()
+	exports := path.Join(dir, "exports")
+	if err := os.Mkdir(exports, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(exports, "sprint=12.csv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		value       string
+		wantInput   string
+		wantProject string
+	}{
+		{"exports/sprint=12.csv", "exports/sprint=12.csv", ""},
+		{"exports/sprint=12.csv=ProjectA", "exports/sprint=12.csv", "ProjectA"},
+		{"proj.csv=ProjectA", "proj.csv", "ProjectA"},
+		{"proj.csv", "proj.csv", ""},
+		{"https://jira.example.com/export?jql=project=A", "https://jira.example.com/export?jql=project=A", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		input, project := splitInputProject(tt.value)
+		if input != tt.wantInput || project != tt.wantProject {
+			t.Errorf("splitInputProject(%q) = %q, %q, want %q, %q", tt.value, input, project, tt.wantInput, tt.wantProject)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package burnup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Read the cumulative closed points of each date of a Totals file written by the tool
+func readCumulativeClosed(in io.Reader) (map[string]float64, error) {
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to read the totals header: %s", ErrParse, err)
+	}
+	dateNdx, closedNdx := -1, -1
+	for i, name := range header {
+		switch name {
+		case "date":
+			dateNdx = i
+		case "cumulativeClosed":
+			closedNdx = i
+		}
+	}
+	if dateNdx < 0 || closedNdx < 0 {
+		return nil, fmt.Errorf("%w: the totals have no \"date\" and \"cumulativeClosed\" columns", ErrParse)
+	}
+	closed := make(map[string]float64)
+	for {
+		records, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrParse, err)
+		}
+		points, err := strconv.ParseFloat(records[closedNdx], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to convert cumulative closed points of \"%s\" on %s", ErrParse, records[closedNdx], records[dateNdx])
+		}
+		closed[records[dateNdx]] = points
+	}
+	return closed, nil
+}
+
+// WriteVariance compares two Totals files written by the tool, such as last week's and this week's, and writes
+// the change in cumulative closed points from the first to the second for each date they have in common
+func WriteVariance(first io.Reader, second io.Reader, opts Options) error {
+	firstClosed, err := readCumulativeClosed(first)
+	if err != nil {
+		return err
+	}
+	secondClosed, err := readCumulativeClosed(second)
+	if err != nil {
+		return err
+	}
+	var dates []string
+	for date := range firstClosed {
+		if _, ok := secondClosed[date]; ok {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("date", "firstCumulativeClosed", "secondCumulativeClosed", "variance")
+	for _, date := range dates {
+		records.write(date, formatPoints(firstClosed[date], opts), formatPoints(secondClosed[date], opts), formatPoints(secondClosed[date]-firstClosed[date], opts))
+	}
+	o := &runOutputs{
+		dir:       opts.OutputDir,
+		date:      opts.runDate(),
+		atomic:    opts.Atomic,
+		checksums: make(map[string]string),
+		opts:      opts,
+	}
+	return o.writeOutputFile("", "Variance", rendered.String())
+}
@@ -0,0 +1,129 @@
+package burnup
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Format the totals are written in by default
+const FormatCSV = "csv"
+
+// TotalsData is what an output writer renders: a set of running totals along with the options in effect
+// and, where there is one, the backlog the totals were computed from
+type TotalsData struct {
+	Totals  *Totals
+	Backlog *Backlog
+	Options Options
+}
+
+// OutputWriter renders running totals in a particular format
+type OutputWriter interface {
+	Extension() string                        // File name extension of the rendered totals
+	Write(w io.Writer, data TotalsData) error // Render the totals
+}
+
+// Output writers keyed by the name of their format
+var outputWriters = map[string]OutputWriter{
+	FormatCSV:          csvTotalsWriter{},
+	ChartSVG:           svgChartWriter{},
+	FormatXLSXCombined: xlsxCombinedWriter{},
+	FormatHTML:         htmlReportWriter{},
+}
+
+// RegisterOutputWriter registers an output writer under the name of its format, replacing any writer already
+// registered under that name
+func RegisterOutputWriter(format string, writer OutputWriter) {
+	outputWriters[format] = writer
+}
+
+// Formats returns the names of the registered output formats in alphabetical order
+func Formats() []string {
+	formats := make([]string, 0, len(outputWriters))
+	for format := range outputWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Writes the totals table as CSV
+type csvTotalsWriter struct{}
+
+func (csvTotalsWriter) Extension() string {
+	return "csv"
+}
+
+func (csvTotalsWriter) Write(w io.Writer, data TotalsData) error {
+	_, err := io.WriteString(w, renderTotals(data.Totals, data.Options))
+	return err
+}
+
+// Writes the burn-up chart as SVG
+type svgChartWriter struct{}
+
+func (svgChartWriter) Extension() string {
+	return "svg"
+}
+
+func (svgChartWriter) Write(w io.Writer, data TotalsData) error {
+	_, err := io.WriteString(w, renderChartSVG(data.Totals, data.Options))
+	return err
+}
+
+// Write totals into the Totals subdirectory of the output directory in the format selected in the options,
+// split into a file for each fiscal quarter when totalling by quarter.  Monthly totals go to the top level instead
+func (o *runOutputs) writePeriodTotals(kind string, data TotalsData) error {
+	if data.Options.Period == PeriodMonthly {
+		return o.writeMonthlyTotals(kind, data)
+	}
+	if data.Options.Period != PeriodQuarterly {
+		return o.writeTotals("Totals", kind, data.Options.Format, data)
+	}
+	for _, quarter := range data.Totals.fiscalQuarters(data.Options.FiscalStartMonth) {
+		quarterData := data
+		quarterData.Totals = quarter.totals
+		err := o.writeTotals("Totals", kind+" "+quarter.label, data.Options.Format, quarterData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write totals into a subdirectory of the output directory in the named format
+func (o *runOutputs) writeTotals(dir string, kind string, format string, data TotalsData) error {
+	writer, ok := outputWriters[format]
+	if !ok {
+		return fmt.Errorf("%w: unknown output format \"%s\"", ErrValidation, format)
+	}
+	out, err := o.create(dir, kind, writer.Extension())
+	if err != nil {
+		return err
+	}
+	return writeTotalsFile(out, writer, data)
+}
+
+// Write monthly totals into the top level of the output directory under the bare kind, which is neither dated
+// nor run through the name template so that each run replaces the last
+func (o *runOutputs) writeMonthlyTotals(kind string, data TotalsData) error {
+	writer, ok := outputWriters[data.Options.Format]
+	if !ok {
+		return fmt.Errorf("%w: unknown output format \"%s\"", ErrValidation, data.Options.Format)
+	}
+	out, err := o.createNamed("", kind+"."+writer.Extension())
+	if err != nil {
+		return err
+	}
+	return writeTotalsFile(out, writer, data)
+}
+
+// Write totals to an output file in the given writer's format and close it
+func writeTotalsFile(out *outputFile, writer OutputWriter, data TotalsData) error {
+	err := writer.Write(out, data)
+	if err != nil {
+		out.close()
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	return out.close()
+}
@@ -0,0 +1,3 @@
+module github.com/ptdecker/burnup
+
+go 1.21
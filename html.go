@@ -0,0 +1,70 @@
+package burnup
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// Format writing a self-contained HTML report of the burn-up chart and summary in place of the totals table
+const FormatHTML = "html"
+
+// Report page with the chart inlined as SVG and no external assets or scripts
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { text-align: left; padding: 0.25em 1em 0.25em 0; }
+td { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{.Chart}}
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// A line of the report's summary table
+type reportLine struct {
+	Name  string
+	Value string
+}
+
+// Writes the burn-up chart and a summary of the totals as a single HTML page
+type htmlReportWriter struct{}
+
+func (htmlReportWriter) Extension() string {
+	return "html"
+}
+
+func (htmlReportWriter) Write(w io.Writer, data TotalsData) error {
+	totals, opts := data.Totals, data.Options
+	title := "Burn-up " + opts.runDate().Format(isoDate)
+	if opts.Project != "" {
+		title = opts.Project + " " + title
+	}
+	summary := []reportLine{
+		{"Total scope points", formatPoints(totals.TotalPoints, opts)},
+		{"Total closed points", formatPoints(totals.ClosedPoints, opts)},
+		{"Percent complete", fmt.Sprintf("%.1f%%", totals.percentComplete())},
+		{"First activity", formatDate(totals.FirstDate)},
+		{"Last activity", formatDate(totals.LastDate)},
+		{"Leaf items", fmt.Sprint(totals.LeafItems)},
+	}
+	if data.Backlog != nil {
+		summary = append(summary, reportLine{"Leaf items without points", fmt.Sprint(len(unpointedItems(data.Backlog)))})
+	}
+	return reportTemplate.Execute(w, struct {
+		Title   string
+		Chart   template.HTML
+		Summary []reportLine
+	}{title, template.HTML(renderChartSVG(totals, opts)), summary})
+}
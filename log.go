@@ -0,0 +1,114 @@
+package burnup
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Levels of logged messages
+const LevelInfo = "INFO"
+const LevelWarning = "WARNING"
+const LevelError = "ERROR"
+const LevelFatal = "FATAL"
+
+// Categories of warnings counted for the end of run summary
+const warnBadPoints = "bad points"
+const warnSuspectPoints = "suspect points"
+const warnAmbiguousPoints = "ambiguous points"
+const warnBadCreated = "bad created date"
+const warnBadResolved = "bad resolved date"
+const warnInvertedDates = "resolved before created"
+const warnAssumedClosed = "assumed closed"
+const warnDuplicate = "duplicate"
+const warnIDCollision = "issue id collision"
+const warnMissingColumn = "missing column"
+const warnCircularParent = "circular parent"
+const warnSelfParent = "self parent"
+const warnNegativeRemaining = "negative remaining"
+const warnFutureDate = "future date"
+
+// WarningTally counts the warnings logged in each category over a run
+type WarningTally struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Count a warning in the given category
+func (t *WarningTally) add(category string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counts == nil {
+		t.counts = make(map[string]int)
+	}
+	t.counts[category]++
+}
+
+// LogSummary logs the number of warnings in each category, most frequent first, logging nothing when there
+// were none
+func (t *WarningTally) LogSummary(opts Options) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	categories := make([]string, 0, len(t.counts))
+	for category := range t.counts {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if t.counts[categories[i]] != t.counts[categories[j]] {
+			return t.counts[categories[i]] > t.counts[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	for _, category := range categories {
+		Logf(opts, LevelInfo, "", 0, "%d warning(s) of type \"%s\"", t.counts[category], category)
+	}
+}
+
+// A logged message as written when logging JSON lines
+type logEntry struct {
+	Level   string `json:"level"`
+	Msg     string `json:"msg"`
+	IssueID string `json:"issueId,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// Logf logs a message at the given level, either as free text or as a single line JSON object when
+// opts.LogJSON is set.  The issue id and input line the message is about are left out when empty or zero
+func Logf(opts Options, level string, issueID string, line int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if !opts.LogJSON {
+		log.Printf("%s: %s", level, msg)
+		return
+	}
+	entry, err := json.Marshal(logEntry{Level: level, Msg: msg, IssueID: issueID, Line: line})
+	if err != nil {
+		log.Printf("%s: %s", level, msg)
+		return
+	}
+	fmt.Fprintln(log.Writer(), string(entry))
+}
+
+// Log a warning of the given category about the issue on the given input line, counting it in the tally of
+// warnings when there is one
+func warnf(opts Options, category string, issueID string, line int, format string, args ...interface{}) {
+	if opts.Warnings != nil {
+		opts.Warnings.add(category)
+	}
+	Logf(opts, LevelWarning, issueID, line, format, args...)
+}
+
+// Log an error in the input data which the run works around, counting it in the tally of warnings along with
+// those of its category
+func errorf(opts Options, category string, issueID string, line int, format string, args ...interface{}) {
+	if opts.Warnings != nil {
+		opts.Warnings.add(category)
+	}
+	Logf(opts, LevelError, issueID, line, format, args...)
+}
+
+// Log an informational message
+func infof(opts Options, format string, args ...interface{}) {
+	Logf(opts, LevelInfo, "", 0, format, args...)
+}
@@ -0,0 +1,1111 @@
+package burnup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// Record of a run written alongside the output files for audit purposes
+type runManifest struct {
+	Timestamp     string            `json:"timestamp"`
+	Inputs        []string          `json:"inputs"`
+	Flags         map[string]string `json:"flags"`
+	RowsProcessed int               `json:"rowsProcessed"`
+	Outputs       map[string]string `json:"outputs"` // SHA-256 checksum of each output file keyed by file name
+}
+
+// Writes the output files of a single run, all named for the date of the run, keeping track of the
+// checksums of the files written for the run manifest
+type runOutputs struct {
+	dir       string
+	date      time.Time
+	atomic    bool
+	checksums map[string]string
+	opts      Options
+}
+
+// An output file being streamed to disk through a buffer whose checksum is recorded for the run manifest
+// once it is closed
+type outputFile struct {
+	*bufio.Writer
+	name      string
+	tempName  string // Temporary file renamed into place on close when writing atomically
+	file      *os.File
+	hash      hash.Hash
+	checksums map[string]string
+}
+
+// Text field of an output record, quoted unless minimal quoting is in use
+type csvText string
+
+// Writes the records of an output CSV file.  Text fields are quoted, with any quotes they hold doubled, unless
+// minimal quoting is in use in which case encoding/csv quotes only the fields that need it
+type csvWriter struct {
+	w           io.Writer
+	minimal     *csv.Writer
+	noQuote     bool
+	bareHeaders bool // Column names are only quoted when they need it, whatever the quoting of the data
+}
+
+// Create a CSV writer quoting as selected in the options
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	return &csvWriter{
+		w:           w,
+		minimal:     csv.NewWriter(w),
+		noQuote:     opts.NoQuote,
+		bareHeaders: opts.BareHeaders,
+	}
+}
+
+// Write a record made up of text fields and already formatted values
+func (c *csvWriter) write(fields ...interface{}) {
+	if c.noQuote {
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = fmt.Sprint(field)
+		}
+		c.minimal.Write(record)
+		c.minimal.Flush()
+		return
+	}
+	for i, field := range fields {
+		if i > 0 {
+			io.WriteString(c.w, ",")
+		}
+		if text, ok := field.(csvText); ok {
+			io.WriteString(c.w, "\""+strings.Replace(string(text), "\"", "\"\"", -1)+"\"")
+		} else {
+			fmt.Fprint(c.w, field)
+		}
+	}
+	io.WriteString(c.w, "\n")
+}
+
+// Write a header record of column names
+func (c *csvWriter) header(names ...string) {
+	if c.bareHeaders {
+		c.minimal.Write(names)
+		c.minimal.Flush()
+		return
+	}
+	fields := make([]interface{}, len(names))
+	for i, name := range names {
+		fields[i] = csvText(name)
+	}
+	c.write(fields...)
+}
+
+// Create a directory if it does not already exist
+// c.f.  https://siongui.github.io/2017/03/28/go-create-directory-if-not-exist/
+func createDirIfNotExist(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("%w: unable to create directory \"%s\": %s", ErrWrite, dir, err)
+		}
+	}
+	return nil
+}
+
+// Kinds of output written into each directory under the output directory, the top level being ""
+var outputKinds = map[string][]string{
+	"":          {"Chart", "Drilldown", "Flow Metrics", "Report", "Rollup", "Scope Changes", "Throughput", "Variance", "Velocity By Sprint"},
+	"Snapshots": {"Backlog Snapshot"},
+	"Audits":    {"Aging", "Below Min Points", "Labels", "Missing Parents", "No Points Closed", "No Points Open", "No Points Summary", "No Points by Assignee", "Point Distribution", "Resolved Parents"},
+	"Totals":    {"Totals"},
+}
+
+// Pattern matching the names of the output files the tool writes into a directory under the output directory,
+// which are named by the name template for one of the kinds written there and the run date.  The totals may be
+// followed by the group or project they are for
+func outputFileName(dir string, opts Options) (*regexp.Regexp, error) {
+	name, err := outputName("\x00kind\x00", "\x00date\x00", opts)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make([]string, len(outputKinds[dir]))
+	for i, kind := range outputKinds[dir] {
+		kinds[i] = regexp.QuoteMeta(kind)
+	}
+	kind := "(" + strings.Join(kinds, "|") + ")"
+	if dir == "Totals" {
+		kind += `( - .+| ScopeAtClose)?`
+	}
+	pattern := regexp.QuoteMeta(name)
+	pattern = strings.Replace(pattern, "\x00kind\x00", kind, -1)
+	pattern = strings.Replace(pattern, "\x00date\x00", `\d{4}-\d{2}-\d{2}`, -1)
+	return regexp.Compile(`^` + pattern + `\.(csv|svg|xlsx|html|json)$`)
+}
+
+// DefaultNameTemplate names each output file for its kind followed by the run date
+const DefaultNameTemplate = "{{.Kind}} {{.Date}}"
+
+// Fields available to the output file name template
+type nameFields struct {
+	Kind    string // Kind of output, such as Totals or No Points
+	Date    string // Run date as YYYY-MM-DD
+	Project string // Name of the project
+}
+
+// Name an output file, without its extension, for its kind and date using the name template
+func outputName(kind string, date string, opts Options) (string, error) {
+	names, err := template.New("name").Option("missingkey=error").Parse(opts.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid name template: %s", ErrValidation, err)
+	}
+	var name strings.Builder
+	err = names.Execute(&name, nameFields{Kind: kind, Date: date, Project: opts.Project})
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid name template: %s", ErrValidation, err)
+	}
+	return name.String(), nil
+}
+
+// Check that the name template gives each kind of output a distinct name which can be used as a file name
+func checkNameTemplate(opts Options) error {
+	totals, err := outputName("Totals", "2006-01-02", opts)
+	if err != nil {
+		return err
+	}
+	audit, err := outputName("No Points", "2006-01-02", opts)
+	if err != nil {
+		return err
+	}
+	if totals == audit {
+		return fmt.Errorf("%w: the name template must include {{.Kind}} so that each output has its own name", ErrValidation)
+	}
+	if strings.ContainsAny(totals+audit, "/\\\x00") || strings.HasPrefix(totals, ".") {
+		return fmt.Errorf("%w: the name template must give names that are neither hidden nor contain a path separator", ErrValidation)
+	}
+	return nil
+}
+
+// Remove the output files of earlier runs from the output directory and its subdirectories, leaving any other
+// files alone
+func truncateOutputs(opts Options) error {
+	for _, dir := range []string{"", "Snapshots", "Audits", "Totals"} {
+		fileName, err := outputFileName(dir, opts)
+		if err != nil {
+			return err
+		}
+		dir = path.Join(opts.OutputDir, dir)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrWrite, err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !fileName.MatchString(entry.Name()) {
+				continue
+			}
+			err = os.Remove(path.Join(dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrWrite, err)
+			}
+			infof(opts, "Removed %s", path.Join(dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// CheckOutputDir checks that the output directory and each of its subdirectories can be created and written
+// to, so that a run fails before the input is read rather than after all of the work is done
+func CheckOutputDir(opts Options) error {
+	for _, dir := range []string{"", "Snapshots", "Audits", "Totals"} {
+		dir = path.Join(opts.OutputDir, dir)
+		err := createDirIfNotExist(dir)
+		if err != nil {
+			return err
+		}
+		probe, err := os.CreateTemp(dir, ".probe-*.tmp")
+		if err != nil {
+			return fmt.Errorf("%w: directory \"%s\" is not writable: %s", ErrWrite, dir, err)
+		}
+		probe.Close()
+		err = os.Remove(probe.Name())
+		if err != nil {
+			return fmt.Errorf("%w: directory \"%s\" is not writable: %s", ErrWrite, dir, err)
+		}
+	}
+	return nil
+}
+
+// Create an output file named for its kind and the run date in a subdirectory of the output directory
+func (o *runOutputs) create(dir string, kind string, ext string) (*outputFile, error) {
+	name, err := outputName(kind, o.date.Format(isoDate), o.opts)
+	if err != nil {
+		return nil, err
+	}
+	return o.createNamed(dir, name+"."+ext)
+}
+
+// Create an output file with exactly the given name in a subdirectory of the output directory, for the outputs
+// named the same from run to run.  No new file is started once the run has been interrupted
+func (o *runOutputs) createNamed(dir string, name string) (*outputFile, error) {
+	if o.opts.interrupted() {
+		return nil, fmt.Errorf("%w: stopped before writing %s", ErrInterrupted, name)
+	}
+	err := createDirIfNotExist(path.Join(o.dir, dir))
+	if err != nil {
+		return nil, err
+	}
+	fileName := path.Join(o.dir, dir, name)
+	var file *os.File
+	var tempName string
+	if o.atomic {
+		file, err = os.CreateTemp(path.Join(o.dir, dir), "."+name+"-*.tmp")
+		if err == nil {
+			tempName = file.Name()
+			err = file.Chmod(0644)
+		}
+	} else {
+		file, err = os.Create(fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	checksum := sha256.New()
+	return &outputFile{
+		Writer:    bufio.NewWriter(io.MultiWriter(file, checksum)),
+		name:      fileName,
+		tempName:  tempName,
+		file:      file,
+		hash:      checksum,
+		checksums: o.checksums,
+	}, nil
+}
+
+// Write a file named for its kind and the run date into a subdirectory of the output directory
+func (o *runOutputs) writeFile(dir string, kind string, ext string, contents []byte) error {
+	out, err := o.create(dir, kind, ext)
+	if err != nil {
+		return err
+	}
+	out.Write(contents)
+	return out.close()
+}
+
+// Write an output CSV file named for its kind and the run date into a subdirectory of the output directory
+func (o *runOutputs) writeOutputFile(dir string, kind string, contents string) error {
+	return o.writeFile(dir, kind, "csv", []byte(contents))
+}
+
+// Flush and close an output file recording its checksum.  When writing atomically the temporary file is
+// renamed into place so that readers only ever see a complete file
+func (out *outputFile) close() error {
+	err := out.Flush()
+	if err == nil {
+		err = out.file.Close()
+	} else {
+		out.file.Close()
+	}
+	if err == nil && out.tempName != "" {
+		err = os.Rename(out.tempName, out.name)
+	}
+	if err != nil {
+		if out.tempName != "" {
+			os.Remove(out.tempName)
+		}
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	out.checksums[out.name] = fmt.Sprintf("%x", out.hash.Sum(nil))
+	return nil
+}
+
+// WriteOutputs writes the backlog snapshot, the audits, the running totals and the run manifest into
+// subdirectories of the output directory
+func WriteOutputs(backlog *Backlog, totals *Totals, opts Options) error {
+	o := &runOutputs{
+		dir:       opts.OutputDir,
+		date:      opts.runDate(),
+		atomic:    opts.Atomic,
+		checksums: make(map[string]string),
+		opts:      opts,
+	}
+
+	if opts.Truncate {
+		err := truncateOutputs(opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The combined workbook carries the snapshot and the no points audit as sheets alongside the totals.  A
+	// backlog read back from a snapshot already has one
+	var err error
+	if opts.Format != FormatXLSXCombined {
+		if !backlog.FromSnapshot {
+			err = writeSnapshot(o, backlog, opts)
+			if err != nil {
+				return err
+			}
+		}
+		err = writeNoPoints(o, backlog, opts)
+		if err != nil {
+			return err
+		}
+	}
+	if backlog.HasAssignees {
+		err = o.writeOutputFile("Audits", "No Points by Assignee", renderNoPointsByAssignee(backlog, opts))
+		if err != nil {
+			return err
+		}
+	}
+	err = o.writeOutputFile("Audits", "Aging", renderAging(backlog, o.date, opts))
+	if err != nil {
+		return err
+	}
+	if opts.AuditParents {
+		err = o.writeOutputFile("Audits", "Resolved Parents", renderResolvedParents(backlog, opts))
+		if err != nil {
+			return err
+		}
+	}
+	err = o.writeOutputFile("Audits", "Labels", renderLabels(backlog, opts))
+	if err != nil {
+		return err
+	}
+	err = o.writeOutputFile("Audits", "Point Distribution", renderPointDistribution(backlog, opts))
+	if err != nil {
+		return err
+	}
+	if opts.MinPoints > 0 {
+		err = o.writeOutputFile("Audits", "Below Min Points", renderBelowMinPoints(backlog, opts))
+		if err != nil {
+			return err
+		}
+	}
+	err = o.writeOutputFile("Audits", "Missing Parents", renderMissingParents(backlog, opts))
+	if err != nil {
+		return err
+	}
+	totalsKind := "Totals"
+	if opts.Period == PeriodMonthly {
+		totalsKind = "Totals Monthly"
+	}
+	if opts.TotalsOut != nil {
+		err = outputWriters[opts.Format].Write(opts.TotalsOut, TotalsData{Totals: totals, Backlog: backlog, Options: opts})
+		if err != nil {
+			err = fmt.Errorf("%w: %s", ErrWrite, err)
+		}
+	} else if opts.Format == FormatHTML {
+		err = o.writeTotals("", "Report", opts.Format, TotalsData{Totals: totals, Backlog: backlog, Options: opts})
+	} else {
+		err = o.writePeriodTotals(totalsKind, TotalsData{Totals: totals, Backlog: backlog, Options: opts})
+	}
+	if err != nil {
+		return err
+	}
+	if opts.GroupBy != "" {
+		for group, groupBacklog := range backlog.partition() {
+			groupTotals := ComputeTotals(groupBacklog, opts)
+			err = o.writePeriodTotals(totalsKind+" - "+safeFileName(group), TotalsData{Totals: groupTotals, Backlog: groupBacklog, Options: opts})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if opts.ByProject {
+		for project, projectBacklog := range backlog.partitionBy(func(item backlogItem) string { return item.project }) {
+			err = o.writePeriodTotals(totalsKind+" - Project "+safeFileName(project), TotalsData{Totals: ComputeTotals(projectBacklog, opts), Backlog: projectBacklog, Options: opts})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if opts.ScopeAt == ScopeAtClosed {
+		err = o.writePeriodTotals(totalsKind+" ScopeAtClose", TotalsData{Totals: computeTotals(backlog, opts, true), Options: opts})
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Chart == ChartSVG {
+		err = o.writeTotals("", "Chart", ChartSVG, TotalsData{Totals: totals, Options: opts})
+		if err != nil {
+			return err
+		}
+	}
+	err = o.writeOutputFile("", "Drilldown", renderDrilldown(totals, opts))
+	if err != nil {
+		return err
+	}
+	err = o.writeOutputFile("", "Rollup", renderRollup(backlog, opts))
+	if err != nil {
+		return err
+	}
+	err = o.writeOutputFile("", "Scope Changes", renderScopeChanges(backlog, opts))
+	if err != nil {
+		return err
+	}
+	err = o.writeOutputFile("", "Flow Metrics", renderFlowMetrics(backlog, opts))
+	if err != nil {
+		return err
+	}
+	err = o.writeOutputFile("", "Throughput", renderThroughput(backlog, opts))
+	if err != nil {
+		return err
+	}
+	if backlog.HasSprints {
+		err = o.writeOutputFile("", "Velocity By Sprint", velocityBySprint(backlog.items, opts))
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.History != "" {
+		err = appendHistory(o.date, totals, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeManifest(o, backlog, opts)
+}
+
+// Append the run date and grand totals to the history file, creating it with a header when it does not yet
+// exist, so that the history builds up a time series across runs
+func appendHistory(date time.Time, totals *Totals, opts Options) error {
+	_, err := os.Stat(opts.History)
+	newFile := os.IsNotExist(err)
+	file, err := os.OpenFile(opts.History, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	records := newCSVWriter(file, opts)
+	if newFile {
+		records.header("date", "scopePoints", "closedPoints", "percentComplete")
+	}
+	records.write(date.Format(isoDate), formatPoints(totals.TotalPoints, opts), formatPoints(totals.ClosedPoints, opts), fmt.Sprintf("%.2f", totals.percentComplete()))
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	return nil
+}
+
+// Write the backlog snapshot listing only the leaf items
+func writeSnapshot(o *runOutputs, backlog *Backlog, opts Options) error {
+	snapshot, err := o.create("Snapshots", "Backlog Snapshot", "csv")
+	if err != nil {
+		return err
+	}
+	renderSnapshot(snapshot, backlog, o.date, opts)
+	return snapshot.close()
+}
+
+// Render the backlog snapshot of leaf items, aging open items as of the run date
+func renderSnapshot(w io.Writer, backlog *Backlog, now time.Time, opts Options) {
+	records := newCSVWriter(w, opts)
+	header := []string{"type", "id", "opened", "closed", "points", "isOpen", "ageDays"}
+	if opts.ByProject {
+		header = append(header, "project")
+	}
+	records.header(header...)
+	for _, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		isOpen := item.closed.Equal(time.Time{})
+		if !isOpen && opts.Snapshot == SnapshotOpenOnly {
+			continue
+		}
+		ageDays := ""
+		if !item.opened.Equal(time.Time{}) {
+			end := item.closed
+			if isOpen {
+				end = now
+			}
+			ageDays = strconv.Itoa(int(end.Sub(item.opened).Hours() / 24))
+		}
+		fields := []interface{}{csvText(item.itemType), csvText(outputID(item.id, opts)), csvText(item.opened.Format(isoDate)), csvText(formatDate(item.closed)), formatPoints(item.points, opts), isOpen, ageDays}
+		if opts.ByProject {
+			fields = append(fields, csvText(item.project))
+		}
+		records.write(fields...)
+	}
+}
+
+// Write the audit listing leaf items missing points, including those whose points could not be parsed.  Open
+// items still need estimating and are split from the closed ones, which are only of historical interest.  The
+// counts of each go in a summary of their own so that both lists stay plain CSV
+func writeNoPoints(o *runOutputs, backlog *Backlog, opts Options) error {
+	open, closed := splitUnpointed(unpointedItems(backlog))
+	for _, audit := range []struct {
+		kind  string
+		items []backlogItem
+	}{{"No Points Open", open}, {"No Points Closed", closed}} {
+		noPoints, err := o.create("Audits", audit.kind, "csv")
+		if err != nil {
+			return err
+		}
+		renderNoPoints(noPoints, audit.items, opts)
+		err = noPoints.close()
+		if err != nil {
+			return err
+		}
+	}
+	return o.writeOutputFile("Audits", "No Points Summary", renderNoPointsSummary(open, closed, opts))
+}
+
+// Split the leaf items missing points into those still open and those closed
+func splitUnpointed(unpointed []backlogItem) (open, closed []backlogItem) {
+	for _, item := range unpointed {
+		if item.closed.Equal(time.Time{}) {
+			open = append(open, item)
+		} else {
+			closed = append(closed, item)
+		}
+	}
+	return open, closed
+}
+
+// Render the number of leaf items missing points that are open, closed and in all
+func renderNoPointsSummary(open, closed []backlogItem, opts Options) string {
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("state", "items")
+	records.write(csvText("open"), len(open))
+	records.write(csvText("closed"), len(closed))
+	records.write(csvText("total"), len(open)+len(closed))
+	return rendered.String()
+}
+
+// Leaf items missing points, including those whose points could not be parsed
+func unpointedItems(backlog *Backlog) []backlogItem {
+	var unpointed []backlogItem
+	for _, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		if item.points != 0 && !item.invalidPoints {
+			continue
+		}
+		unpointed = append(unpointed, item)
+	}
+	return unpointed
+}
+
+// Render the audit of leaf items missing points
+func renderNoPoints(w io.Writer, unpointed []backlogItem, opts Options) {
+	sortAudit(unpointed, opts.AuditSort)
+	records := newCSVWriter(w, opts)
+	records.header("type", "id", "closed", "invalidPoints")
+	for _, item := range unpointed {
+		records.write(csvText(item.itemType), csvText(outputID(item.id, opts)), !item.closed.Equal(time.Time{}), item.invalidPoints)
+	}
+}
+
+// Render the audit of leaf items missing points grouped by the person they are assigned to, as a grooming
+// worklist for each assignee
+func renderNoPointsByAssignee(backlog *Backlog, opts Options) string {
+	unpointed := unpointedItems(backlog)
+	sort.Slice(unpointed, func(i, j int) bool {
+		if unpointed[i].assignee != unpointed[j].assignee {
+			return unpointed[i].assignee < unpointed[j].assignee
+		}
+		return unpointed[i].id < unpointed[j].id
+	})
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("assignee", "type", "id", "closed", "invalidPoints")
+	for _, item := range unpointed {
+		records.write(csvText(item.assignee), csvText(item.itemType), csvText(outputID(item.id, opts)), !item.closed.Equal(time.Time{}), item.invalidPoints)
+	}
+	return rendered.String()
+}
+
+// Sort the items of an audit by id, by opened date newest first or by type, breaking ties by id
+func sortAudit(items []backlogItem, order string) {
+	sort.Slice(items, func(i, j int) bool {
+		switch order {
+		case AuditSortOpened:
+			if !items[i].opened.Equal(items[j].opened) {
+				return items[i].opened.After(items[j].opened)
+			}
+		case AuditSortType:
+			if items[i].itemType != items[j].itemType {
+				return items[i].itemType < items[j].itemType
+			}
+		}
+		return items[i].id < items[j].id
+	})
+}
+
+// Render the audit of open leaf items with how many days they have been open as of the run date, oldest first
+func renderAging(backlog *Backlog, now time.Time, opts Options) string {
+	var openItems []backlogItem
+	for _, item := range backlog.items {
+		if item.hasChildren || !item.closed.Equal(time.Time{}) {
+			continue
+		}
+		openItems = append(openItems, item)
+	}
+	sort.Slice(openItems, func(i, j int) bool {
+		if !openItems[i].opened.Equal(openItems[j].opened) {
+			return openItems[i].opened.Before(openItems[j].opened)
+		}
+		return openItems[i].id < openItems[j].id
+	})
+	var aging strings.Builder
+	records := newCSVWriter(&aging, opts)
+	records.header("type", "id", "opened", "ageDays")
+	for _, item := range openItems {
+		records.write(csvText(item.itemType), csvText(outputID(item.id, opts)), csvText(formatDate(item.opened)), int(now.Sub(item.opened).Hours()/24))
+	}
+	return aging.String()
+}
+
+// Render the audit of resolved parents with their resolution dates.  Parents carry no points but their
+// resolution marks the completion of a milestone
+func renderResolvedParents(backlog *Backlog, opts Options) string {
+	var parents []backlogItem
+	for _, item := range backlog.items {
+		if !item.hasChildren || item.id == "" || item.closed.Equal(time.Time{}) {
+			continue
+		}
+		parents = append(parents, item)
+	}
+	sort.Slice(parents, func(i, j int) bool {
+		if !parents[i].closed.Equal(parents[j].closed) {
+			return parents[i].closed.Before(parents[j].closed)
+		}
+		return parents[i].id < parents[j].id
+	})
+	var resolved strings.Builder
+	records := newCSVWriter(&resolved, opts)
+	records.header("type", "id", "closed")
+	for _, item := range parents {
+		records.write(csvText(item.itemType), csvText(outputID(item.id, opts)), csvText(item.closed.Format(isoDate)))
+	}
+	return resolved.String()
+}
+
+// Render the audit of leaf items dropped for carrying fewer points than the minimum
+func renderBelowMinPoints(backlog *Backlog, opts Options) string {
+	dropped := append([]backlogItem(nil), backlog.belowMin...)
+	sortAudit(dropped, opts.AuditSort)
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("type", "id", "opened", "closed", "points")
+	for _, item := range dropped {
+		records.write(csvText(item.itemType), csvText(outputID(item.id, opts)), csvText(formatDate(item.opened)), csvText(formatDate(item.closed)), formatPoints(item.points, opts))
+	}
+	return rendered.String()
+}
+
+// Render the parent keys referenced by items but never backed by a record of their own, such as parents in
+// another project's export, along with the items referencing them
+func renderMissingParents(backlog *Backlog, opts Options) string {
+	children := make(map[string][]string)
+	for _, item := range backlog.items {
+		if item.parent == "" {
+			continue
+		}
+		if parentItem, ok := backlog.items[item.parent]; ok && parentItem.id == "" {
+			children[item.parent] = append(children[item.parent], outputID(item.id, opts))
+		}
+	}
+	parentKeys := make([]string, 0, len(children))
+	for parentKey := range children {
+		parentKeys = append(parentKeys, parentKey)
+		sort.Strings(children[parentKey])
+	}
+	sort.Strings(parentKeys)
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("parentKey", "referencedBy")
+	for _, parentKey := range parentKeys {
+		records.write(csvText(outputID(parentKey, opts)), csvText(strings.Join(children[parentKey], " ")))
+	}
+	return rendered.String()
+}
+
+// Render the item level events the totals were aggregated from, one row for each item opened, closed or
+// closed in part on a date, so that the points of each day's rows add up to those of the totals
+func renderDrilldown(totals *Totals, opts Options) string {
+	events := append([]itemEvent(nil), totals.events...)
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].date.Equal(events[j].date) {
+			return events[i].date.Before(events[j].date)
+		}
+		if events[i].event != events[j].event {
+			return events[i].event > events[j].event
+		}
+		return events[i].id < events[j].id
+	})
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("date", "event", "type", "id", "points")
+	for _, event := range events {
+		records.write(event.date.Format(isoDate), csvText(event.event), csvText(event.itemType), csvText(outputID(event.id, opts)), formatPoints(event.points, opts))
+	}
+	return rendered.String()
+}
+
+// Render the number of leaf items carrying each distinct story point value, smallest first
+func renderPointDistribution(backlog *Backlog, opts Options) string {
+	counts := make(map[float64]int)
+	for _, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		counts[item.points]++
+	}
+	values := make([]float64, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Float64s(values)
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("points", "items")
+	for _, value := range values {
+		records.write(formatPoints(value, opts), counts[value])
+	}
+	return rendered.String()
+}
+
+// Render each label used on leaf items with the number of items carrying it and the sum of their points,
+// largest first.  An item with several labels counts towards each of them
+func renderLabels(backlog *Backlog, opts Options) string {
+	labelPoints := make(map[string]float64)
+	labelItems := make(map[string]int)
+	for _, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		for _, label := range strings.Fields(item.tags) {
+			labelPoints[label] += item.points
+			labelItems[label]++
+		}
+	}
+	labels := make([]string, 0, len(labelItems))
+	for label := range labelItems {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labelPoints[labels[i]] != labelPoints[labels[j]] {
+			return labelPoints[labels[i]] > labelPoints[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("label", "items", "points")
+	for _, label := range labels {
+		records.write(csvText(label), labelItems[label], formatPoints(labelPoints[label], opts))
+	}
+	return rendered.String()
+}
+
+// Render each parent with the points of its leaf descendants rolled up into it.  Parents stay out of the
+// totals so that points are not double counted, this is for epic level reporting only
+func renderRollup(backlog *Backlog, opts Options) string {
+	rollup := backlog.rolledUpPoints()
+	var parentKeys []string
+	for key, item := range backlog.items {
+		if item.hasChildren && item.id != "" {
+			parentKeys = append(parentKeys, key)
+		}
+	}
+	sort.Slice(parentKeys, func(i, j int) bool {
+		return backlog.items[parentKeys[i]].id < backlog.items[parentKeys[j]].id
+	})
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("type", "id", "rolledUpPoints")
+	for _, key := range parentKeys {
+		item := backlog.items[key]
+		records.write(csvText(item.itemType), csvText(outputID(item.id, opts)), formatPoints(rollup[key], opts))
+	}
+	return rendered.String()
+}
+
+// Render the points each leaf item added to scope on the day it was opened, grouped by day.  This is the
+// opened pivot at the item level.  The export does not record when items are removed from scope, so only
+// additions can be listed
+func renderScopeChanges(backlog *Backlog, opts Options) string {
+	var added []backlogItem
+	for _, item := range backlog.items {
+		if item.hasChildren || item.points <= 0 {
+			continue
+		}
+		added = append(added, item)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		if added[i].opened.Format(isoDate) != added[j].opened.Format(isoDate) {
+			return added[i].opened.Before(added[j].opened)
+		}
+		return added[i].id < added[j].id
+	})
+	var changes strings.Builder
+	records := newCSVWriter(&changes, opts)
+	records.header("date", "type", "id", "pointsAdded")
+	for _, item := range added {
+		records.write(item.opened.Format(isoDate), csvText(item.itemType), csvText(outputID(item.id, opts)), formatPoints(item.points, opts))
+	}
+	return changes.String()
+}
+
+// Render the totals table as CSV, ending each row with the baseline when one is in use
+func renderTotals(totals *Totals, opts Options) string {
+	var rendered strings.Builder
+	// When stacking by type the opened and closed points are broken down into a pair of columns per type
+	stacked := opts.StackBy == StackByType
+	header := []string{"date", "pointsOpened", "pointsClosed"}
+	if stacked {
+		header = header[:1]
+		for _, itemType := range totals.types {
+			header = append(header, itemType+"_opened", itemType+"_closed")
+		}
+	}
+	// The gap between scope and done is pointsRemaining under the name the chart readers use; the two are aliases
+	header = append(header, "pointsRemaining", "gap", "cumulativeOpened", "cumulativeClosed", "percentComplete", "itemsOpened", "itemsClosed")
+	if opts.Baseline {
+		header = append(header, "baseline")
+	}
+
+	var rows [][]interface{}
+	for _, row := range totals.rows {
+		fields := []interface{}{row.date.Format(isoDate)}
+		if stacked {
+			for _, itemType := range totals.types {
+				fields = append(fields, formatPoints(row.openedByType[itemType], opts), formatPoints(row.closedByType[itemType], opts))
+			}
+		} else {
+			fields = append(fields, formatPoints(row.pointsOpened, opts), formatPoints(row.pointsClosed, opts))
+		}
+		fields = append(fields, formatPoints(row.pointsRemaining, opts), formatPoints(row.pointsRemaining, opts), formatPoints(row.cumulativeOpened, opts), formatPoints(row.cumulativeClosed, opts), fmt.Sprintf("%.2f", row.percentComplete), row.itemsOpened, row.itemsClosed)
+		if opts.Baseline {
+			fields = append(fields, formatPoints(totals.Baseline, opts))
+		}
+		rows = append(rows, fields)
+	}
+
+	records := newCSVWriter(&rendered, opts)
+	if !opts.Transpose {
+		records.header(header...)
+		for _, fields := range rows {
+			records.write(fields...)
+		}
+		return rendered.String()
+	}
+
+	// Transposed, the dates run across the top and each of the other columns becomes a row
+	dates := []string{"metric"}
+	for _, fields := range rows {
+		dates = append(dates, fields[0].(string))
+	}
+	records.header(dates...)
+	for i, name := range header[1:] {
+		fields := []interface{}{csvText(name)}
+		for _, row := range rows {
+			fields = append(fields, row[i+1])
+		}
+		records.write(fields...)
+	}
+	return rendered.String()
+}
+
+// Render the number of leaf items closed in each ISO week, whatever their points.  Every week from the first
+// to the last close is included, even those without any, so that the series is continuous
+func renderThroughput(backlog *Backlog, opts Options) string {
+	weekClosed := make(map[string]int)
+	var firstWeek, lastWeek time.Time
+	for _, item := range backlog.items {
+		if item.hasChildren || item.closed.Equal(time.Time{}) {
+			continue
+		}
+		week := startOfWeek(item.closed)
+		weekClosed[isoWeek(week)]++
+		if firstWeek.IsZero() || week.Before(firstWeek) {
+			firstWeek = week
+		}
+		if lastWeek.IsZero() || week.After(lastWeek) {
+			lastWeek = week
+		}
+	}
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("week", "weekStarting", "itemsClosed")
+	if firstWeek.IsZero() {
+		return rendered.String()
+	}
+	for week := firstWeek; !week.After(lastWeek); week = week.AddDate(0, 0, 7) {
+		records.write(isoWeek(week), week.Format(isoDate), weekClosed[isoWeek(week)])
+	}
+	return rendered.String()
+}
+
+// Midnight on the Monday starting the ISO week a date falls in
+func startOfWeek(date time.Time) time.Time {
+	daysSinceMonday := (int(date.Weekday()) + 6) % 7
+	return time.Date(date.Year(), date.Month(), date.Day()-daysSinceMonday, 0, 0, 0, 0, date.Location())
+}
+
+// Percentiles of lead time reported in the flow metrics
+var flowPercentiles = []float64{50, 85, 95}
+
+// Render the percentiles of lead time in days, from opened to closed, across the leaf items with both dates
+func renderFlowMetrics(backlog *Backlog, opts Options) string {
+	var leadTimes []float64
+	for _, item := range backlog.items {
+		if item.hasChildren || item.opened.Equal(time.Time{}) || item.closed.Equal(time.Time{}) {
+			continue
+		}
+		leadTimes = append(leadTimes, item.closed.Sub(item.opened).Hours()/24)
+	}
+	sort.Float64s(leadTimes)
+	var rendered strings.Builder
+	records := newCSVWriter(&rendered, opts)
+	records.header("percentile", "leadTimeDays", "items")
+	if len(leadTimes) == 0 {
+		return rendered.String()
+	}
+	for _, percentile := range flowPercentiles {
+		records.write(percentile, fmt.Sprintf("%.2f", interpolatePercentile(leadTimes, percentile)), len(leadTimes))
+	}
+	return rendered.String()
+}
+
+// Percentile of sorted values, interpolating linearly between the values either side of its rank
+func interpolatePercentile(sorted []float64, percentile float64) float64 {
+	rank := percentile / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// Format the ISO week a date falls in, e.g. 2020-W10
+func isoWeek(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Render the closed points of leaf items summed per sprint.  Sprints are listed in the order in which their
+// first item was closed
+func velocityBySprint(backlogMap map[string]backlogItem, opts Options) string {
+	const noSprint = "(no sprint)"
+	sprintPoints := make(map[string]float64)
+	sprintStart := make(map[string]time.Time)
+	for _, item := range backlogMap {
+		if item.hasChildren || item.closed.Equal(time.Time{}) {
+			continue
+		}
+		sprint := item.sprint
+		if sprint == "" {
+			sprint = noSprint
+		}
+		sprintPoints[sprint] += item.points
+		if start, ok := sprintStart[sprint]; !ok || item.closed.Before(start) {
+			sprintStart[sprint] = item.closed
+		}
+	}
+	sprints := make([]string, 0, len(sprintPoints))
+	for sprint := range sprintPoints {
+		sprints = append(sprints, sprint)
+	}
+	sort.Slice(sprints, func(i, j int) bool {
+		if !sprintStart[sprints[i]].Equal(sprintStart[sprints[j]]) {
+			return sprintStart[sprints[i]].Before(sprintStart[sprints[j]])
+		}
+		return sprints[i] < sprints[j]
+	})
+	var velocity strings.Builder
+	records := newCSVWriter(&velocity, opts)
+	records.header("sprint", "pointsClosed")
+	for _, sprint := range sprints {
+		records.write(csvText(sprint), formatPoints(sprintPoints[sprint], opts))
+	}
+	return velocity.String()
+}
+
+// Name of the file in the output directory holding the run manifest, which each run replaces rather than dating
+const manifestName = "manifest.json"
+
+// Write the run manifest recording when the tool was run, with what options, against which input and the
+// checksums of each of the files it produced
+func writeManifest(o *runOutputs, backlog *Backlog, opts Options) error {
+	manifest := runManifest{
+		Timestamp:     time.Now().Format(time.RFC3339),
+		Inputs:        opts.Inputs,
+		Flags:         opts.Flags,
+		RowsProcessed: backlog.RowsProcessed,
+		Outputs:       o.checksums,
+	}
+	contents, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: unable to build manifest: %s", ErrWrite, err)
+	}
+	manifestFile, err := o.createNamed("", manifestName)
+	if err != nil {
+		return err
+	}
+	manifestFile.Write(contents)
+	return manifestFile.close()
+}
+
+// WriteSummary writes a human readable recap of the totals as an aligned text table
+func WriteSummary(w io.Writer, totals *Totals, opts Options) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Total scope points\t%.*f\n", opts.Precision, totals.TotalPoints)
+	fmt.Fprintf(tw, "Total closed points\t%.*f\n", opts.Precision, totals.ClosedPoints)
+	fmt.Fprintf(tw, "Percent complete\t%.1f%%\n", totals.percentComplete())
+	fmt.Fprintf(tw, "First activity\t%s\n", formatDate(totals.FirstDate))
+	fmt.Fprintf(tw, "Last activity\t%s\n", formatDate(totals.LastDate))
+	fmt.Fprintf(tw, "Leaf items\t%d\n", totals.LeafItems)
+	if opts.Baseline {
+		fmt.Fprintf(tw, "Baseline points before %s\t%.*f\n", opts.Start.Format(isoDate), opts.Precision, totals.Baseline)
+	}
+	return tw.Flush()
+}
+
+// Make a value taken from the export safe to use as part of a file name
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// Identify an item in the output files, replacing its id with the first 8 hex digits of its SHA-256 when
+// anonymizing so that an item maps to the same token on every run without exposing its id
+func outputID(id string, opts Options) string {
+	if !opts.Anonymize {
+		return id
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(id)))[:8]
+}
+
+// Format a point value to the precision selected in the options
+func formatPoints(points float64, opts Options) string {
+	return strconv.FormatFloat(points, 'f', opts.Precision, 64)
+}
+
+// Format a date as ISO 8601 leaving unset dates blank
+func formatDate(date time.Time) string {
+	if date.Equal(time.Time{}) {
+		return ""
+	}
+	return date.Format(isoDate)
+}
@@ -0,0 +1,371 @@
+package burnup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Read rendered CSV back into its records, failing the test when it is not plain CSV
+func readTestCSV(t *testing.T, rendered string) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(rendered)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v\n%s", err, rendered)
+	}
+	return records
+}
+
+// Find a column of a header, failing the test when it is missing
+func testColumn(t *testing.T, header []string, name string) int {
+	t.Helper()
+	for i, column := range header {
+		if column == name {
+			return i
+		}
+	}
+	t.Fatalf("no %s column in %v", name, header)
+	return -1
+}
+
+func TestBaselineColumn(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.Start = time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)
+	opts.Baseline = true
+	totals := ComputeTotals(parseTestBacklog(t, testRows, opts), opts)
+	records := readTestCSV(t, renderTotals(totals, opts))
+	baseline := testColumn(t, records[0], "baseline")
+	closed := testColumn(t, records[0], "cumulativeClosed")
+	if records[1][0] != "2024-03-03" {
+		t.Fatalf("first row is for %s, want 2024-03-03", records[1][0])
+	}
+	for _, record := range records[1:] {
+		if record[baseline] != "0.00" {
+			t.Errorf("baseline on %s = %s, want 0.00 as nothing was both opened and closed before the start", record[0], record[baseline])
+		}
+	}
+
+	opts.Start = time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	totals = ComputeTotals(parseTestBacklog(t, testRows+"P-6,6,Story,To Do,07/Mar/24 09:00 AM,,,1,\n", opts), opts)
+	records = readTestCSV(t, renderTotals(totals, opts))
+	if got := records[1][baseline]; got != "3.00" {
+		t.Errorf("baseline = %s, want the 3.00 points of P-2", got)
+	}
+	if got := records[1][closed]; got != "5.00" {
+		t.Errorf("cumulativeClosed on the start = %s, want 5.00 starting from the baseline", got)
+	}
+
+	opts.Baseline = false
+	records = readTestCSV(t, renderTotals(totals, opts))
+	for _, column := range records[0] {
+		if column == "baseline" {
+			t.Errorf("baseline column written without -baseline")
+		}
+	}
+}
+
+func TestResolvedParents(t *testing.T) {
+	const rows = "P-1,1,Epic,Done,01/Mar/24 09:00 AM,06/Mar/24 09:00 AM,,8,\n" +
+		"P-2,2,Story,Done,02/Mar/24 09:00 AM,04/Mar/24 09:00 AM,,3,1\n" +
+		"P-3,3,Epic,In Progress,01/Mar/24 09:00 AM,,,5,\n" +
+		"P-4,4,Story,Done,02/Mar/24 09:00 AM,05/Mar/24 09:00 AM,,2,3\n"
+	captureLog(t)
+	opts := testOptions()
+	backlog := parseTestBacklog(t, rows, opts)
+	records := readTestCSV(t, renderResolvedParents(backlog, testOptions()))
+	want := [][]string{{"type", "id", "closed"}, {"Epic", "P-1", "2024-03-06"}}
+	if len(records) != len(want) {
+		t.Fatalf("audit = %v, want %v", records, want)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("audit row %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+	if totals := ComputeTotals(backlog, opts); totals.TotalPoints != 5 || totals.ClosedPoints != 5 {
+		t.Errorf("totals = %g points of which %g closed, want the 5 points of the stories alone", totals.TotalPoints, totals.ClosedPoints)
+	}
+}
+
+func TestCSVEscaping(t *testing.T) {
+	const rows = "P-1,1,\"Bug, \"\"major\"\"\",To Do,01/Mar/24 09:00 AM,,,3,\n"
+	for _, noQuote := range []bool{false, true} {
+		captureLog(t)
+		opts := testOptions()
+		opts.NoQuote = noQuote
+		opts.OutputDir = t.TempDir()
+		backlog := parseTestBacklog(t, rows, opts)
+		if err := WriteOutputs(backlog, ComputeTotals(backlog, opts), opts); err != nil {
+			t.Fatalf("no quote %v: WriteOutputs() error = %v", noQuote, err)
+		}
+		snapshot, err := os.ReadFile(path.Join(opts.OutputDir, "Snapshots", "Backlog Snapshot "+time.Now().Format(isoDate)+".csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := readTestCSV(t, string(snapshot))
+		if got := records[1][testColumn(t, records[0], "type")]; got != `Bug, "major"` {
+			t.Errorf("no quote %v: snapshot type = %q, want %q", noQuote, got, `Bug, "major"`)
+		}
+	}
+}
+
+func TestTruncateOutputs(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.OutputDir = t.TempDir()
+	files := map[string]bool{
+		"Rollup 2024-03-01.csv":                       true,
+		"Chart 2024-03-01.svg":                        true,
+		"Scope Changes 2024-03-01.csv":                true,
+		"Snapshots/Backlog Snapshot 2024-03-01.csv":   true,
+		"Audits/No Points Open 2024-03-01.csv":        true,
+		"Audits/No Points by Assignee 2024-03-01.csv": true,
+		"Audits/Missing Parents 2024-03-01.csv":       true,
+		"Totals/Totals 2024-03-01.csv":                true,
+		"Totals/Totals - Team A 2024-03-01.csv":       true,
+		"Totals/Totals ScopeAtClose 2024-03-01.csv":   true,
+		"Notes 2024-03-01.csv":                        false,
+		"Audits/Notes 2024-03-01.csv":                 false,
+		"Totals/Budget 2024-03-01.csv":                false,
+		"Snapshots/Totals 2024-03-01.csv":             false,
+		"Rollup 2024-03-01.csv.bak":                   false,
+		"History.csv":                                 false,
+		".last-input.sha256":                          false,
+		"manifest.json":                               false,
+		"Totals Monthly.csv":                          false,
+		"Totals Monthly - Team A.csv":                 false,
+	}
+	for name := range files {
+		name = path.Join(opts.OutputDir, name)
+		if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := truncateOutputs(opts); err != nil {
+		t.Fatalf("truncateOutputs() error = %v", err)
+	}
+	for name, wantRemoved := range files {
+		_, err := os.Stat(path.Join(opts.OutputDir, name))
+		if removed := os.IsNotExist(err); removed != wantRemoved {
+			t.Errorf("%s removed = %v, want %v", name, removed, wantRemoved)
+		}
+	}
+}
+
+func TestManifestName(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.OutputDir = t.TempDir()
+	opts.NameTemplate = "TeamA_{{.Kind}}_{{.Date}}"
+	backlog := parseTestBacklog(t, testRows, opts)
+	for run := 0; run < 2; run++ {
+		if err := WriteOutputs(backlog, ComputeTotals(backlog, opts), opts); err != nil {
+			t.Fatalf("WriteOutputs() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(path.Join(opts.OutputDir, "manifest.json")); err != nil {
+		t.Errorf("manifest not written as manifest.json: %v", err)
+	}
+	entries, err := os.ReadDir(opts.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "Manifest") {
+			t.Errorf("manifest also written as %s", entry.Name())
+		}
+	}
+}
+
+func TestMonthlyTotalsName(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.OutputDir = t.TempDir()
+	opts.NameTemplate = "TeamA_{{.Kind}}_{{.Date}}"
+	opts.Period = PeriodMonthly
+	backlog := parseTestBacklog(t, testRows, opts)
+	if err := WriteOutputs(backlog, ComputeTotals(backlog, opts), opts); err != nil {
+		t.Fatalf("WriteOutputs() error = %v", err)
+	}
+	if _, err := os.Stat(path.Join(opts.OutputDir, "Totals Monthly.csv")); err != nil {
+		t.Errorf("monthly totals not written as Totals Monthly.csv: %v", err)
+	}
+	entries, err := os.ReadDir(path.Join(opts.OutputDir, "Totals"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("monthly totals also written as Totals/%s", entry.Name())
+	}
+}
+
+func TestTransposedTotals(t *testing.T) {
+	captureLog(t)
+	tests := []struct {
+		name    string
+		stackBy string
+	}{
+		{"flat", ""},
+		{"stacked by type", StackByType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.StackBy = tt.stackBy
+			totals := ComputeTotals(parseTestBacklog(t, testRows, opts), opts)
+			normal := readTestCSV(t, renderTotals(totals, opts))
+			opts.Transpose = true
+			transposed := readTestCSV(t, renderTotals(totals, opts))
+
+			if len(transposed) != len(normal[0]) {
+				t.Fatalf("%d transposed rows, want one for the dates and one per column: %d", len(transposed), len(normal[0]))
+			}
+			if transposed[0][0] != "metric" {
+				t.Errorf("transposed header starts %q, want metric", transposed[0][0])
+			}
+			for i, record := range transposed {
+				if len(record) != len(normal) {
+					t.Fatalf("transposed row %d has %d fields, want one per date plus its name: %d", i, len(record), len(normal))
+				}
+				if i > 0 && record[0] != normal[0][i] {
+					t.Errorf("transposed row %d is named %q, want %q", i, record[0], normal[0][i])
+				}
+				for j := 1; j < len(record); j++ {
+					if record[j] != normal[j][i] {
+						t.Errorf("transposed %s on %s = %q, want %q", normal[0][i], normal[j][0], record[j], normal[j][i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNoPointsAudit(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	backlog := parseTestBacklog(t, testRows+
+		"P-6,6,Bug,Done,02/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,,\n"+
+		"P-7,7,Story,To Do,02/Mar/24 09:00 AM,,,,\n", opts)
+	o := &runOutputs{
+		dir:       t.TempDir(),
+		date:      time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC),
+		checksums: make(map[string]string),
+		opts:      opts,
+	}
+	if err := os.MkdirAll(path.Join(o.dir, "Audits"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNoPoints(o, backlog, opts); err != nil {
+		t.Fatalf("writeNoPoints() error = %v", err)
+	}
+
+	tests := []struct {
+		kind string
+		want [][]string
+	}{
+		{"No Points Open", [][]string{{"type", "id", "closed", "invalidPoints"}, {"Task", "P-5", "false", "false"}, {"Story", "P-7", "false", "false"}}},
+		{"No Points Closed", [][]string{{"type", "id", "closed", "invalidPoints"}, {"Bug", "P-6", "true", "false"}}},
+		{"No Points Summary", [][]string{{"state", "items"}, {"open", "2"}, {"closed", "1"}, {"total", "3"}}},
+	}
+	for _, tt := range tests {
+		contents, err := os.ReadFile(path.Join(o.dir, "Audits", tt.kind+" 2024-03-06.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := readTestCSV(t, string(contents))
+		if !reflect.DeepEqual(records, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.kind, records, tt.want)
+		}
+	}
+}
+
+func TestCombinedWorkbookSheets(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	backlog := parseTestBacklog(t, testRows, opts)
+	var workbook bytes.Buffer
+	err := xlsxCombinedWriter{}.Write(&workbook, TotalsData{Totals: ComputeTotals(backlog, opts), Backlog: backlog, Options: opts})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(workbook.Bytes()), int64(workbook.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheets []string
+	styled := false
+	for _, file := range archive.File {
+		if file.Name == "xl/styles.xml" {
+			styled = true
+		}
+		if file.Name != "xl/workbook.xml" {
+			continue
+		}
+		contents, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parsed struct {
+			Sheets []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"sheets>sheet"`
+		}
+		err = xml.NewDecoder(contents).Decode(&parsed)
+		contents.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sheet := range parsed.Sheets {
+			sheets = append(sheets, sheet.Name)
+		}
+	}
+	want := []string{"Totals", "Backlog Snapshot", "No Points Open", "No Points Closed", "No Points Summary"}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("sheets = %q, want %q", sheets, want)
+	}
+	if !styled {
+		t.Error("workbook has no xl/styles.xml")
+	}
+}
+
+func TestGapColumn(t *testing.T) {
+	const allClosed = "P-1,1,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,3,\n" +
+		"P-2,2,Bug,Done,02/Mar/24 09:00 AM,05/Mar/24 09:00 AM,,2,\n"
+	tests := []struct {
+		name     string
+		rows     string
+		wantLast string
+	}{
+		{"work left open", testRows, "5.00"},
+		{"all work closed", allClosed, "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			totals := ComputeTotals(parseTestBacklog(t, tt.rows, opts), opts)
+			records := readTestCSV(t, renderTotals(totals, opts))
+			gap := testColumn(t, records[0], "gap")
+			remaining := testColumn(t, records[0], "pointsRemaining")
+			for _, record := range records[1:] {
+				if record[gap] != record[remaining] {
+					t.Errorf("gap on %s = %s, want pointsRemaining %s", record[0], record[gap], record[remaining])
+				}
+			}
+			if last := records[len(records)-1]; last[gap] != tt.wantLast {
+				t.Errorf("gap on %s = %s, want %s", last[0], last[gap], tt.wantLast)
+			}
+		})
+	}
+}
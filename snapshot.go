@@ -0,0 +1,109 @@
+package burnup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ParseSnapshot reads a backlog snapshot written by an earlier run back into a backlog of its leaf items, so
+// that the totals and audits can be recomputed without parsing the JIRA export and walking its hierarchy again
+func ParseSnapshot(in io.Reader, opts Options) (*Backlog, error) {
+	backlog := &Backlog{
+		items:        make(map[string]backlogItem),
+		FromSnapshot: true,
+	}
+	zone := opts.Zone
+	if zone == nil {
+		zone = time.UTC
+	}
+
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err == io.EOF {
+		return backlog, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrParse, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"type", "id", "opened", "closed", "points"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: the snapshot has no \"%s\" column", ErrParse, name)
+		}
+	}
+
+	for {
+		records, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrParse, err)
+		}
+		if opts.interrupted() {
+			return nil, fmt.Errorf("%w: stopped while reading row %d", ErrInterrupted, backlog.RowsProcessed+1)
+		}
+		backlog.RowsProcessed++
+		line, _ := r.FieldPos(0)
+		id := records[columns["id"]]
+		if opts.ExcludeKeys[id] {
+			continue
+		}
+		item := backlogItem{
+			itemType: records[columns["type"]],
+			id:       id,
+			project:  opts.Project,
+		}
+		if projectNdx, ok := columns["project"]; ok && records[projectNdx] != "" {
+			item.project = records[projectNdx]
+		}
+		item.opened, err = parseSnapshotDate(records[columns["opened"]], zone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s's opened date on line %d: %s", ErrParse, id, line, err)
+		}
+		item.closed, err = parseSnapshotDate(records[columns["closed"]], zone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s's closed date on line %d: %s", ErrParse, id, line, err)
+		}
+		item.points, err = strconv.ParseFloat(records[columns["points"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s's points on line %d: %s", ErrParse, id, line, err)
+		}
+		item.estimate = item.points
+
+		// Look at the backlog as it stood at the end of the as-of date as when parsing an export
+		if !opts.AsOf.IsZero() {
+			if !item.opened.Before(opts.dayAfterRunDate(zone)) {
+				continue
+			}
+			if !item.closed.Before(opts.dayAfterRunDate(zone)) {
+				item.closed = time.Time{}
+			}
+		}
+
+		if _, ok := backlog.items[id]; ok {
+			warnf(opts, warnDuplicate, id, line, "Encountered an unexpected duplicate item: \"%s\"", id)
+			continue
+		}
+		backlog.items[id] = item
+	}
+
+	if opts.MinPoints > 0 {
+		backlog.dropBelowMinPoints(opts.MinPoints)
+	}
+	return backlog, nil
+}
+
+// Parse a date of a snapshot, which is blank when unset
+func parseSnapshotDate(value string, zone *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(isoDate, value, zone)
+}
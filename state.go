@@ -0,0 +1,52 @@
+package burnup
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Name of the file in the output directory recording the checksum of the input and options of the last run
+const inputStateFile = ".last-input.sha256"
+
+// InputChecksum returns the SHA-256 checksum of an input along with the run date and the flags in effect, so
+// that a run on a later day or with other flags, either of which would change the outputs, is not skipped
+func InputChecksum(input []byte, opts Options) string {
+	checksum := sha256.New()
+	checksum.Write(input)
+	fmt.Fprintf(checksum, "\x00%s", opts.runDate().Format(isoDate))
+	names := make([]string, 0, len(opts.Flags))
+	for name := range opts.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(checksum, "\x00%s=%s", name, opts.Flags[name])
+	}
+	return fmt.Sprintf("%x", checksum.Sum(nil))
+}
+
+// InputUnchanged reports whether an input checksum matches the one recorded by the last run
+func InputUnchanged(checksum string, opts Options) bool {
+	previous, err := os.ReadFile(path.Join(opts.OutputDir, inputStateFile))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(previous)) == checksum
+}
+
+// RecordInput records the checksum of the input of this run so that the next run can tell if it has changed
+func RecordInput(checksum string, opts Options) error {
+	err := createDirIfNotExist(opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path.Join(opts.OutputDir, inputStateFile), []byte(checksum+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWrite, err)
+	}
+	return nil
+}
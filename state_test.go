@@ -0,0 +1,35 @@
+package burnup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInputChecksum(t *testing.T) {
+	input := []byte(testHeader + testRows)
+	base := testOptions()
+	base.AsOf = time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC)
+	base.Flags = map[string]string{"period": "daily", "precision": "2"}
+	checksum := InputChecksum(input, base)
+
+	changedFlag := base
+	changedFlag.Flags = map[string]string{"period": "daily", "precision": "1"}
+	laterDay := base
+	laterDay.AsOf = base.AsOf.AddDate(0, 0, 1)
+	tests := []struct {
+		name  string
+		input []byte
+		opts  Options
+		same  bool
+	}{
+		{"same run", input, base, true},
+		{"changed input", append([]byte(nil), input[1:]...), base, false},
+		{"changed flag", input, changedFlag, false},
+		{"later day", input, laterDay, false},
+	}
+	for _, tt := range tests {
+		if got := InputChecksum(tt.input, tt.opts) == checksum; got != tt.same {
+			t.Errorf("%s: checksum unchanged = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
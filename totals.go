@@ -0,0 +1,413 @@
+package burnup
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Points aggregated for a single day
+type pivotValue struct {
+	date   time.Time
+	points float64
+	items  int
+	byType map[string]float64 // Points broken down by item type
+}
+
+// Add the points of items of a type opened or closed on a date to a pivot value
+func (value pivotValue) add(date time.Time, itemType string, points float64, items int) pivotValue {
+	if value.byType == nil {
+		value.byType = make(map[string]float64)
+	}
+	value.date = date
+	value.points += points
+	value.items += items
+	value.byType[itemType] += points
+	return value
+}
+
+// Merge the points of another pivot value into a pivot value
+func (value pivotValue) merge(other pivotValue) pivotValue {
+	for itemType, points := range other.byType {
+		value = value.add(other.date, itemType, points, 0)
+	}
+	value.items += other.items
+	return value
+}
+
+// Points of a leaf item counted in the totals on a date, as opened, closed or closed in part
+type itemEvent struct {
+	date     time.Time
+	event    string
+	itemType string
+	id       string
+	points   float64
+}
+
+// Events counted in the totals
+const eventOpened = "opened"
+const eventClosed = "closed"
+const eventPartial = "partial"
+const eventReopened = "reopened"
+
+// A single row of the running totals table
+type totalsRow struct {
+	date             time.Time
+	pointsOpened     float64
+	pointsClosed     float64
+	itemsOpened      int
+	itemsClosed      int
+	openedByType     map[string]float64
+	closedByType     map[string]float64
+	pointsRemaining  float64
+	cumulativeOpened float64
+	cumulativeClosed float64
+	percentComplete  float64
+}
+
+// Totals are the running totals of a backlog along with the figures used to summarize it
+type Totals struct {
+	TotalPoints  float64   // Points carried by all leaf items
+	ClosedPoints float64   // Points carried by closed leaf items
+	LeafItems    int       // Number of leaf items
+	FirstDate    time.Time // Date of the first activity
+	LastDate     time.Time // Date of the last activity
+	Baseline     float64   // Points opened and closed before the reporting window carried into the cumulative values
+
+	rows   []totalsRow
+	types  []string    // Types of the items counted in the rows, in alphabetical order
+	events []itemEvent // Item level events the rows were aggregated from
+}
+
+// ComputeTotals aggregates the points opened and closed in a backlog into running totals for the period
+// selected in the options
+func ComputeTotals(backlog *Backlog, opts Options) *Totals {
+	return computeTotals(backlog, opts, false)
+}
+
+// Aggregate the points opened and closed in a backlog into running totals.  When scope is counted at close,
+// the points of closed items are counted as opened on the day they were closed rather than the day they were
+// opened
+func computeTotals(backlog *Backlog, opts Options, scopeAtClose bool) *Totals {
+	totals := &Totals{}
+
+	for _, item := range backlog.items {
+		if item.hasChildren {
+			continue
+		}
+		totals.TotalPoints += item.points
+		totals.LeafItems++
+		if !item.closed.Equal(time.Time{}) && !item.reopened {
+			totals.ClosedPoints += item.points
+		}
+		totals.ClosedPoints += item.partialPoints()
+	}
+
+	// Aggregate the backlog by date
+	openPivot := make(map[string]pivotValue)
+	closedPivot := make(map[string]pivotValue)
+	firstDate := time.Time{}
+	lastDate := time.Time{}
+	carriedScope := 0.0
+	carriedClosed := 0.0
+	reopenDate := opts.runDate()
+
+	for _, item := range backlog.items {
+
+		// Skip any items with no points
+		if item.points > 0.0 {
+
+			// Shift closes later to show a visible gap for items opened and closed on the same day
+			if opts.CloseLag > 0 && !item.closed.Equal(time.Time{}) {
+				item.closed = item.closed.AddDate(0, 0, opts.CloseLag)
+			}
+
+			if scopeAtClose && !item.closed.Equal(time.Time{}) {
+				item.opened = item.closed
+			}
+
+			// Leave out activity after the reporting window, treating items closed after it as still open
+			if !opts.End.IsZero() {
+				windowEnd := opts.End.AddDate(0, 0, 1)
+				if !item.opened.Before(windowEnd) {
+					continue
+				}
+				if !item.closed.Before(windowEnd) {
+					item.closed = time.Time{}
+				}
+			}
+
+			// Leave out activity before the reporting window.  Items opened before it but still open at its
+			// start are carried into the starting scope, while the points of items both opened and closed
+			// before it are only carried into the starting cumulative values when a baseline is asked for
+			openedBefore := !opts.Start.IsZero() && item.opened.Before(opts.Start)
+			closedBefore := !opts.Start.IsZero() && !item.closed.Equal(time.Time{}) && item.closed.Before(opts.Start)
+			if openedBefore && closedBefore {
+				if opts.Baseline {
+					totals.Baseline += item.points
+				}
+				continue
+			}
+
+			// Accumulate points opened on each day
+			if openedBefore {
+				carriedScope += item.points
+			} else {
+				openPivot[item.opened.Format(isoDate)] = openPivot[item.opened.Format(isoDate)].add(item.opened, item.itemType, item.points, 1)
+				totals.events = append(totals.events, itemEvent{item.opened, eventOpened, item.itemType, item.id, item.points})
+				if firstDate.Equal(time.Time{}) || firstDate.After(item.opened) {
+					firstDate = item.opened
+				}
+				if lastDate.Equal(time.Time{}) || lastDate.Before(item.opened) {
+					lastDate = item.opened
+				}
+			}
+
+			// Accumulate points closed on each day
+			if !item.closed.Equal(time.Time{}) {
+				closedPivot[item.closed.Format(isoDate)] = closedPivot[item.closed.Format(isoDate)].add(item.closed, item.itemType, item.points, 1)
+				totals.events = append(totals.events, itemEvent{item.closed, eventClosed, item.itemType, item.id, item.points})
+				if firstDate.Equal(time.Time{}) || firstDate.After(item.closed) {
+					firstDate = item.closed
+				}
+				if lastDate.Equal(time.Time{}) || lastDate.Before(item.closed) {
+					lastDate = item.closed
+				}
+			}
+
+			// Take the points of reopened items back out of those closed on the run date
+			if item.reopened && !item.closed.Equal(time.Time{}) && (opts.End.IsZero() || reopenDate.Before(opts.End.AddDate(0, 0, 1))) {
+				if !opts.Start.IsZero() && reopenDate.Before(opts.Start) {
+					carriedClosed -= item.points
+				} else {
+					closedPivot[reopenDate.Format(isoDate)] = closedPivot[reopenDate.Format(isoDate)].add(reopenDate, item.itemType, -item.points, 0)
+					totals.events = append(totals.events, itemEvent{reopenDate, eventReopened, item.itemType, item.id, -item.points})
+					if lastDate.Equal(time.Time{}) || lastDate.Before(reopenDate) {
+						lastDate = reopenDate
+					}
+				}
+			}
+
+			// Accumulate the partial progress of items still open on the day they were last updated
+			partial := item.partialPoints()
+			if partial > 0 && (opts.End.IsZero() || item.updated.Before(opts.End.AddDate(0, 0, 1))) {
+				if !opts.Start.IsZero() && item.updated.Before(opts.Start) {
+					carriedClosed += partial
+				} else {
+					closedPivot[item.updated.Format(isoDate)] = closedPivot[item.updated.Format(isoDate)].add(item.updated, item.itemType, partial, 0)
+					totals.events = append(totals.events, itemEvent{item.updated, eventPartial, item.itemType, item.id, partial})
+					if firstDate.Equal(time.Time{}) || firstDate.After(item.updated) {
+						firstDate = item.updated
+					}
+					if lastDate.Equal(time.Time{}) || lastDate.Before(item.updated) {
+						lastDate = item.updated
+					}
+				}
+			}
+		}
+	}
+	totals.FirstDate = firstDate
+	totals.LastDate = lastDate
+	totals.types = pivotTypes(openPivot, closedPivot)
+
+	// Generate running totals table bounded by the reporting window when one is given, padding the days
+	// without activity with zeros
+	if !opts.Start.IsZero() {
+		firstDate = opts.Start
+		if lastDate.Before(firstDate) {
+			lastDate = firstDate
+		}
+	}
+	if !opts.End.IsZero() {
+		lastDate = opts.End
+		if firstDate.Equal(time.Time{}) {
+			firstDate = lastDate
+		}
+	}
+	if opts.Period == PeriodMonthly {
+		totals.rows = monthlyTotals(openPivot, closedPivot, firstDate, lastDate)
+	} else {
+		totals.rows = dailyTotals(openPivot, closedPivot, firstDate, lastDate)
+	}
+	accumulateTotals(totals.rows, totals.Baseline+carriedScope, totals.Baseline+carriedClosed, opts)
+	if opts.SkipEmptyDays {
+		totals.rows = skipEmptyRows(totals.rows, !opts.End.IsZero())
+	}
+
+	return totals
+}
+
+// Verify checks that the points opened and closed over all of the rows add up to the total and closed points of
+// the leaf items, which guards against points being lost or double counted by the aggregation.  It only holds
+// when no reporting window leaves activity out of the rows
+func (totals *Totals) Verify(opts Options) error {
+	const epsilon = 1e-6
+	var opened, closed float64
+	for _, row := range totals.rows {
+		opened += row.pointsOpened
+		closed += row.pointsClosed
+	}
+	if math.Abs(opened-totals.TotalPoints) > epsilon || math.Abs(closed-totals.ClosedPoints) > epsilon {
+		return fmt.Errorf("%w: the rows add up to %.*f points opened and %.*f closed but the leaf items carry %.*f points of which %.*f are closed",
+			ErrValidation, opts.Precision, opened, opts.Precision, closed, opts.Precision, totals.TotalPoints, opts.Precision, totals.ClosedPoints)
+	}
+	if opts.Verbose {
+		infof(opts, "Verified %.*f points opened and %.*f closed", opts.Precision, opened, opts.Precision, closed)
+	}
+	return nil
+}
+
+// Totals of a single fiscal quarter
+type quarterTotals struct {
+	label  string
+	totals *Totals
+}
+
+// Split the rows of the totals into a set of totals for each fiscal quarter they cover, in date order.  The
+// cumulative values carry on from one quarter to the next
+func (totals *Totals) fiscalQuarters(startMonth time.Month) []quarterTotals {
+	var quarters []quarterTotals
+	first := 0
+	for i := range totals.rows {
+		label := fiscalQuarter(totals.rows[i].date, startMonth)
+		if i+1 < len(totals.rows) && fiscalQuarter(totals.rows[i+1].date, startMonth) == label {
+			continue
+		}
+		quarter := *totals
+		quarter.rows = totals.rows[first : i+1]
+		quarters = append(quarters, quarterTotals{label: label, totals: &quarter})
+		first = i + 1
+	}
+	return quarters
+}
+
+// Name the fiscal quarter a date falls in, e.g. Q1 FY24, for a fiscal year starting in the given month.  A
+// fiscal year is named for the calendar year it ends in
+func fiscalQuarter(date time.Time, startMonth time.Month) string {
+	monthsIn := (int(date.Month()) - int(startMonth) + 12) % 12
+	fiscalYear := date.Year()
+	if startMonth != time.January && date.Month() >= startMonth {
+		fiscalYear++
+	}
+	return fmt.Sprintf("Q%d FY%02d", monthsIn/3+1, fiscalYear%100)
+}
+
+// Percent of the total points that are closed, zero when there are no points
+func (totals *Totals) percentComplete() float64 {
+	if totals.TotalPoints == 0 {
+		return 0
+	}
+	return totals.ClosedPoints / totals.TotalPoints * 100
+}
+
+// List the item types found in the pivots in alphabetical order
+func pivotTypes(openPivot map[string]pivotValue, closedPivot map[string]pivotValue) []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, pivot := range []map[string]pivotValue{openPivot, closedPivot} {
+		for _, value := range pivot {
+			for itemType := range value.byType {
+				if !seen[itemType] {
+					seen[itemType] = true
+					types = append(types, itemType)
+				}
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build the totals table with one row per day from the first date through to the last date
+func dailyTotals(openPivot map[string]pivotValue, closedPivot map[string]pivotValue, firstDate time.Time, lastDate time.Time) []totalsRow {
+	var rows []totalsRow
+	if firstDate.Equal(time.Time{}) {
+		return rows
+	}
+	firstDay := time.Date(firstDate.Year(), firstDate.Month(), firstDate.Day(), 0, 0, 0, 0, firstDate.Location())
+	lastDay := time.Date(lastDate.Year(), lastDate.Month(), lastDate.Day(), 0, 0, 0, 0, lastDate.Location())
+	for date := firstDay; !date.After(lastDay); date = date.AddDate(0, 0, 1) {
+		rows = append(rows, totalsRow{
+			date:         date,
+			pointsOpened: openPivot[date.Format(isoDate)].points,
+			pointsClosed: closedPivot[date.Format(isoDate)].points,
+			itemsOpened:  openPivot[date.Format(isoDate)].items,
+			itemsClosed:  closedPivot[date.Format(isoDate)].items,
+			openedByType: openPivot[date.Format(isoDate)].byType,
+			closedByType: closedPivot[date.Format(isoDate)].byType,
+		})
+	}
+	return rows
+}
+
+// Build the totals table bucketed by calendar month.  Every month between the first and last month is
+// included, even those without any activity, so that the series is continuous
+func monthlyTotals(openPivot map[string]pivotValue, closedPivot map[string]pivotValue, firstDate time.Time, lastDate time.Time) []totalsRow {
+	monthOpened := make(map[string]pivotValue)
+	monthClosed := make(map[string]pivotValue)
+	for _, value := range openPivot {
+		monthOpened[value.date.Format(isoMonth)] = monthOpened[value.date.Format(isoMonth)].merge(value)
+	}
+	for _, value := range closedPivot {
+		monthClosed[value.date.Format(isoMonth)] = monthClosed[value.date.Format(isoMonth)].merge(value)
+	}
+	var rows []totalsRow
+	if firstDate.Equal(time.Time{}) {
+		return rows
+	}
+	firstMonth := time.Date(firstDate.Year(), firstDate.Month(), 1, 0, 0, 0, 0, firstDate.Location())
+	lastMonth := time.Date(lastDate.Year(), lastDate.Month(), 1, 0, 0, 0, 0, lastDate.Location())
+	for month := firstMonth; !month.After(lastMonth); month = month.AddDate(0, 1, 0) {
+		rows = append(rows, totalsRow{
+			date:         month,
+			pointsOpened: monthOpened[month.Format(isoMonth)].points,
+			pointsClosed: monthClosed[month.Format(isoMonth)].points,
+			itemsOpened:  monthOpened[month.Format(isoMonth)].items,
+			itemsClosed:  monthClosed[month.Format(isoMonth)].items,
+			openedByType: monthOpened[month.Format(isoMonth)].byType,
+			closedByType: monthClosed[month.Format(isoMonth)].byType,
+		})
+	}
+	return rows
+}
+
+// Fill in the cumulative opened and closed points, starting from the points already opened and closed before the
+// first row, the running remaining points (cumulative opened less cumulative closed) and the percent of the scope
+// so far that is complete (zero until there is any scope) for each row.  A negative remaining value can only
+// happen if points were closed without having been opened, which indicates that closes are being double-counted,
+// so it is warned about
+func accumulateTotals(rows []totalsRow, startOpened float64, startClosed float64, opts Options) {
+	cumulativeOpened := startOpened
+	cumulativeClosed := startClosed
+	warned := false
+	for i := range rows {
+		cumulativeOpened += rows[i].pointsOpened
+		cumulativeClosed += rows[i].pointsClosed
+		remaining := cumulativeOpened - cumulativeClosed
+		rows[i].cumulativeOpened = cumulativeOpened
+		rows[i].cumulativeClosed = cumulativeClosed
+		rows[i].pointsRemaining = remaining
+		if cumulativeOpened > 0 {
+			rows[i].percentComplete = cumulativeClosed / cumulativeOpened * 100
+		}
+		if remaining < 0 && !warned {
+			warnf(opts, warnNegativeRemaining, "", 0, "Remaining points went negative on %s which suggests closed points are double-counted", rows[i].date.Format(isoDate))
+			warned = true
+		}
+	}
+}
+
+// Drop the rows without any points opened or closed, which leaves the cumulative values unchanged from the
+// previous row.  The last row can be kept regardless so that a series padded out to the end of the reporting
+// window still reaches it
+func skipEmptyRows(rows []totalsRow, keepLast bool) []totalsRow {
+	var active []totalsRow
+	for i, row := range rows {
+		if row.pointsOpened == 0 && row.pointsClosed == 0 && !(keepLast && i == len(rows)-1) {
+			continue
+		}
+		active = append(active, row)
+	}
+	return active
+}
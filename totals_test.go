@@ -0,0 +1,221 @@
+package burnup
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Export of a small well-formed backlog: an epic with two stories, a bug and an unpointed task
+const testRows = "P-1,1,Epic,In Progress,01/Mar/24 09:00 AM,,,8,\n" +
+	"P-2,2,Story,Done,02/Mar/24 09:00 AM,04/Mar/24 09:00 AM,,3,1\n" +
+	"P-3,3,Story,In Progress,02/Mar/24 10:00 AM,,,5,1\n" +
+	"P-4,4,Bug,Done,03/Mar/24 09:00 AM,05/Mar/24 09:00 AM,,2,\n" +
+	"P-5,5,Task,To Do,03/Mar/24 09:00 AM,,,,\n"
+
+func TestPointsRemaining(t *testing.T) {
+	tests := []struct {
+		name         string
+		rows         []totalsRow
+		wantEach     []float64
+		wantWarnings int
+	}{
+		{
+			"well formed",
+			[]totalsRow{{pointsOpened: 5}, {pointsOpened: 3, pointsClosed: 2}, {pointsClosed: 6}},
+			[]float64{5, 6, 0},
+			0,
+		},
+		{
+			"double counted closes",
+			[]totalsRow{{pointsOpened: 5}, {pointsClosed: 4}, {pointsClosed: 4}, {pointsClosed: 4}},
+			[]float64{5, 1, -3, -7},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			accumulateTotals(tt.rows, 0, 0, testOptions())
+			for i, row := range tt.rows {
+				if row.pointsRemaining != tt.wantEach[i] {
+					t.Errorf("row %d pointsRemaining = %g, want %g", i, row.pointsRemaining, tt.wantEach[i])
+				}
+			}
+			if got := strings.Count(logged.String(), "Remaining points went negative"); got != tt.wantWarnings {
+				t.Errorf("negative remaining warnings = %d, want %d", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPointsRemainingNeverNegative(t *testing.T) {
+	logged := captureLog(t)
+	opts := testOptions()
+	totals := ComputeTotals(parseTestBacklog(t, testRows, opts), opts)
+	for _, row := range totals.rows {
+		if row.pointsRemaining < 0 {
+			t.Errorf("pointsRemaining on %s = %g, want at least 0", row.date.Format(isoDate), row.pointsRemaining)
+		}
+	}
+	if strings.Contains(logged.String(), "Remaining points went negative") {
+		t.Errorf("logged %q, want no negative remaining warning", logged.String())
+	}
+}
+
+func TestPercentComplete(t *testing.T) {
+	const allClosed = "P-1,1,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,3,\n" +
+		"P-2,2,Story,Done,02/Mar/24 09:00 AM,05/Mar/24 09:00 AM,,5,\n"
+	tests := []struct {
+		name     string
+		rows     string
+		wantLast float64
+	}{
+		{"all closed", allClosed, 100},
+		{"partly closed", testRows, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			opts := testOptions()
+			totals := ComputeTotals(parseTestBacklog(t, tt.rows, opts), opts)
+			last := totals.rows[len(totals.rows)-1]
+			if math.Abs(last.percentComplete-tt.wantLast) > 1e-9 {
+				t.Errorf("percentComplete on %s = %g, want %g", last.date.Format(isoDate), last.percentComplete, tt.wantLast)
+			}
+			for _, row := range totals.rows {
+				if row.percentComplete < 0 || row.percentComplete > 100 {
+					t.Errorf("percentComplete on %s = %g, want 0 to 100", row.date.Format(isoDate), row.percentComplete)
+				}
+			}
+		})
+	}
+}
+
+func TestPercentCompleteWithoutScope(t *testing.T) {
+	captureLog(t)
+	rows := []totalsRow{{}, {pointsOpened: 4}, {pointsClosed: 1}}
+	accumulateTotals(rows, 0, 0, testOptions())
+	for i, want := range []float64{0, 0, 25} {
+		if rows[i].percentComplete != want {
+			t.Errorf("row %d percentComplete = %g, want %g", i, rows[i].percentComplete, want)
+		}
+	}
+}
+
+func TestItemCounts(t *testing.T) {
+	const rows = "P-1,1,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,3,\n" +
+		"P-2,2,Story,Done,01/Mar/24 10:00 AM,03/Mar/24 10:00 AM,,5,\n" +
+		"P-3,3,Story,Done,02/Mar/24 09:00 AM,04/Mar/24 09:00 AM,,8,\n"
+	captureLog(t)
+	opts := testOptions()
+	totals := ComputeTotals(parseTestBacklog(t, rows, opts), opts)
+	want := map[string]struct {
+		pointsOpened, pointsClosed float64
+		itemsOpened, itemsClosed   int
+	}{
+		"2024-03-01": {8, 0, 2, 0},
+		"2024-03-02": {8, 0, 1, 0},
+		"2024-03-03": {0, 8, 0, 2},
+		"2024-03-04": {0, 8, 0, 1},
+	}
+	if len(totals.rows) != len(want) {
+		t.Fatalf("totals have %d rows, want %d", len(totals.rows), len(want))
+	}
+	for _, row := range totals.rows {
+		w := want[row.date.Format(isoDate)]
+		if row.pointsOpened != w.pointsOpened || row.pointsClosed != w.pointsClosed || row.itemsOpened != w.itemsOpened || row.itemsClosed != w.itemsClosed {
+			t.Errorf("%s = %g/%g points from %d/%d items opened/closed, want %g/%g from %d/%d", row.date.Format(isoDate),
+				row.pointsOpened, row.pointsClosed, row.itemsOpened, row.itemsClosed, w.pointsOpened, w.pointsClosed, w.itemsOpened, w.itemsClosed)
+		}
+	}
+}
+
+func TestCloseLag(t *testing.T) {
+	const rows = "P-1,1,Story,Done,01/Mar/24 09:00 AM,01/Mar/24 15:00 PM,,3,\n" +
+		"P-2,2,Story,Done,01/Mar/24 09:00 AM,02/Mar/24 09:00 AM,,5,\n"
+	tests := []struct {
+		lag        int
+		wantClosed map[string]float64
+	}{
+		{0, map[string]float64{"2024-03-01": 3, "2024-03-02": 5}},
+		{1, map[string]float64{"2024-03-02": 3, "2024-03-03": 5}},
+		{3, map[string]float64{"2024-03-04": 3, "2024-03-05": 5}},
+	}
+	for _, tt := range tests {
+		captureLog(t)
+		opts := testOptions()
+		opts.CloseLag = tt.lag
+		totals := ComputeTotals(parseTestBacklog(t, rows, opts), opts)
+		for _, row := range totals.rows {
+			if want := tt.wantClosed[row.date.Format(isoDate)]; row.pointsClosed != want {
+				t.Errorf("lag %d: points closed on %s = %g, want %g", tt.lag, row.date.Format(isoDate), row.pointsClosed, want)
+			}
+			if row.date.Format(isoDate) == "2024-03-01" && row.pointsOpened != 8 {
+				t.Errorf("lag %d: points opened on 2024-03-01 = %g, want 8 unshifted", tt.lag, row.pointsOpened)
+			}
+		}
+		if last := totals.rows[len(totals.rows)-1]; last.cumulativeClosed != 8 {
+			t.Errorf("lag %d: cumulative closed on %s = %g, want 8", tt.lag, last.date.Format(isoDate), last.cumulativeClosed)
+		}
+	}
+}
+
+func TestEndPadding(t *testing.T) {
+	captureLog(t)
+	opts := testOptions()
+	opts.End = time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	totals := ComputeTotals(parseTestBacklog(t, testRows, opts), opts)
+	if got := totals.rows[len(totals.rows)-1].date.Format(isoDate); got != "2024-03-10" {
+		t.Fatalf("last row is for %s, want 2024-03-10", got)
+	}
+	if len(totals.rows) != 9 {
+		t.Errorf("totals have %d rows, want one for each day from the first activity on 2024-03-02 to 2024-03-10", len(totals.rows))
+	}
+	for _, row := range totals.rows {
+		if row.date.Format(isoDate) <= "2024-03-05" {
+			continue
+		}
+		if row.pointsOpened != 0 || row.pointsClosed != 0 || row.cumulativeOpened != 10 || row.cumulativeClosed != 5 {
+			t.Errorf("%s = %g/%g opened/closed and %g/%g cumulative, want 0/0 and a flat 10/5", row.date.Format(isoDate),
+				row.pointsOpened, row.pointsClosed, row.cumulativeOpened, row.cumulativeClosed)
+		}
+	}
+}
+
+func TestMinPoints(t *testing.T) {
+	const rows = "P-1,1,Story,Done,01/Mar/24 09:00 AM,03/Mar/24 09:00 AM,,0.5,\n" +
+		"P-2,2,Story,To Do,01/Mar/24 09:00 AM,,,1,\n" +
+		"P-3,3,Story,Done,02/Mar/24 09:00 AM,04/Mar/24 09:00 AM,,5,\n" +
+		"P-4,4,Task,To Do,02/Mar/24 09:00 AM,,,,\n"
+	tests := []struct {
+		minPoints  float64
+		wantTotal  float64
+		wantClosed float64
+		wantBelow  int
+	}{
+		{0, 6.5, 5.5, 0},
+		{1, 6, 5, 1},
+		{2, 5, 5, 2},
+	}
+	for _, tt := range tests {
+		captureLog(t)
+		opts := testOptions()
+		opts.MinPoints = tt.minPoints
+		backlog := parseTestBacklog(t, rows, opts)
+		totals := ComputeTotals(backlog, opts)
+		if totals.TotalPoints != tt.wantTotal || totals.ClosedPoints != tt.wantClosed {
+			t.Errorf("min points %g: totals = %g of which %g closed, want %g of which %g", tt.minPoints, totals.TotalPoints, totals.ClosedPoints, tt.wantTotal, tt.wantClosed)
+		}
+		if last := totals.rows[len(totals.rows)-1]; last.cumulativeOpened != tt.wantTotal {
+			t.Errorf("min points %g: cumulative opened = %g, want %g", tt.minPoints, last.cumulativeOpened, tt.wantTotal)
+		}
+		if len(backlog.belowMin) != tt.wantBelow {
+			t.Errorf("min points %g: %d items set aside, want %d", tt.minPoints, len(backlog.belowMin), tt.wantBelow)
+		}
+		if _, ok := itemByID(backlog, "P-4"); !ok {
+			t.Errorf("min points %g: unpointed P-4 was dropped rather than left for the no points audit", tt.minPoints)
+		}
+	}
+}
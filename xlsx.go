@@ -0,0 +1,156 @@
+package burnup
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format writing the totals, backlog snapshot and no points audit as sheets of a single workbook
+const FormatXLSXCombined = "xlsx-combined"
+
+// Sheet of a workbook holding the records of one of the CSV outputs
+type workbookSheet struct {
+	name    string
+	records [][]string
+}
+
+// Named part of a workbook, either a file of the package or the CSV rendering of a sheet
+type workbookPart struct {
+	name     string
+	contents string
+}
+
+// Writes the totals as a workbook.  When the backlog is at hand the snapshot and the no points audit are
+// written alongside as sheets of their own
+type xlsxCombinedWriter struct{}
+
+func (xlsxCombinedWriter) Extension() string {
+	return "xlsx"
+}
+
+func (xlsxCombinedWriter) Write(w io.Writer, data TotalsData) error {
+	rendered := []workbookPart{{"Totals", renderTotals(data.Totals, data.Options)}}
+	if data.Backlog != nil {
+		var snapshot, noPointsOpen, noPointsClosed strings.Builder
+		renderSnapshot(&snapshot, data.Backlog, data.Options.runDate(), data.Options)
+		open, closed := splitUnpointed(unpointedItems(data.Backlog))
+		renderNoPoints(&noPointsOpen, open, data.Options)
+		renderNoPoints(&noPointsClosed, closed, data.Options)
+		rendered = append(rendered, workbookPart{"Backlog Snapshot", snapshot.String()},
+			workbookPart{"No Points Open", noPointsOpen.String()}, workbookPart{"No Points Closed", noPointsClosed.String()},
+			workbookPart{"No Points Summary", renderNoPointsSummary(open, closed, data.Options)})
+	}
+	sheets := make([]workbookSheet, 0, len(rendered))
+	for _, sheet := range rendered {
+		r := csv.NewReader(strings.NewReader(sheet.contents))
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		records, err := r.ReadAll()
+		if err != nil {
+			return err
+		}
+		sheets = append(sheets, workbookSheet{name: sheet.name, records: records})
+	}
+	return writeWorkbook(w, sheets)
+}
+
+// Write sheets as an Office Open XML workbook.  Fields that read as numbers are written as numeric cells and
+// everything else as inline text
+func writeWorkbook(w io.Writer, sheets []workbookSheet) error {
+	archive := zip.NewWriter(w)
+	var sheetList, sheetRels, sheetTypes strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), i+1, i+1)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	stylesID := len(sheets) + 1
+	parts := []workbookPart{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheetList.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesID) +
+			`</Relationships>`},
+		{"xl/styles.xml", workbookStyles},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, workbookPart{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), renderWorksheet(sheet.records)})
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(file, part.contents)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// Minimal stylesheet holding just the default font, fill, border and cell format, without which some
+// spreadsheet applications report the workbook as needing repair
+const workbookStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// Field written to a sheet as a number rather than as text
+var numericField = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Render the records of a sheet as worksheet XML
+func renderWorksheet(records [][]string) string {
+	var sheet strings.Builder
+	sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, record := range records {
+		fmt.Fprintf(&sheet, `<row r="%d">`, i+1)
+		for j, field := range record {
+			ref := cellColumn(j) + strconv.Itoa(i+1)
+			if numericField.MatchString(field) {
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, field)
+			} else {
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(field))
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	return sheet.String()
+}
+
+// Name the column of a sheet from its zero-based index, e.g. A, Z, AA
+func cellColumn(ndx int) string {
+	name := ""
+	for ndx++; ndx > 0; ndx = (ndx - 1) / 26 {
+		name = string(rune('A'+(ndx-1)%26)) + name
+	}
+	return name
+}
+
+// Escape text for use in XML content or attribute values
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}